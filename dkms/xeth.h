@@ -655,6 +655,10 @@ const struct ethtool_link_ksettings *
 void xeth_port_ethtool_stat(struct net_device *nd, u32 index, u64 count);
 void xeth_port_link_stat(struct net_device *nd, u32 index, u64 count);
 void xeth_port_speed(struct net_device *nd, u32 mbps);
+void xeth_port_pause(struct net_device *nd,
+		     struct xeth_msg_ethtool_pause *msg);
+void xeth_port_fec(struct net_device *nd, struct xeth_msg_ethtool_fec *msg);
+void xeth_port_eee(struct net_device *nd, struct xeth_msg_ethtool_eee *msg);
 
 void xeth_port_reset_ethtool_stats(struct net_device *);
 
@@ -766,6 +770,12 @@ int xeth_sbtx_change_upper(struct net_device *, u32 upper_xid, u32 lower_xid,
 int xeth_sbtx_et_flags(struct net_device *, u32 xid, u32 flags);
 int xeth_sbtx_et_settings(struct net_device *, u32 xid,
 			  const struct ethtool_link_ksettings *);
+int xeth_sbtx_ethtool_pause(struct net_device *, u32 xid,
+			    const struct ethtool_pauseparam *);
+int xeth_sbtx_ethtool_fec(struct net_device *, u32 xid, u32 fec);
+int xeth_sbtx_ethtool_eee(struct net_device *, u32 xid, u32 advertised,
+			  u32 tx_lpi_timer, bool eee_enabled,
+			  bool tx_lpi_enabled);
 int xeth_sbtx_fib_entry(struct net_device *, struct net *net,
 			struct fib_entry_notifier_info *feni,
 			unsigned long event);
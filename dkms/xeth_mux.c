@@ -44,6 +44,7 @@ struct xeth_mux_priv {
 		spinlock_t mutex;
 		struct list_head free, tx;
 		char rx[XETH_SIZEOF_JUMBO_FRAME];
+		u8 version;	/* of the connected peer */
 	} sb;
 	struct {
 		char names[xeth_mux_max_flags][ETH_GSTRING_LEN];
@@ -706,9 +707,15 @@ static int xeth_mux_sbtx(struct net_device *mux, struct socket *sock,
 		.msg_flags = MSG_DONTWAIT,
 	};
 	struct xeth_msg_netns *ns_msg = iov.iov_base;
+	struct xeth_mux_priv *priv = netdev_priv(mux);
 	struct net *net;
 	int n;
 
+	if (priv->sb.version < xeth_msg_kind_version(ns_msg->header.kind)) {
+		xeth_mux_free_sbtxb(mux, sbtxb);
+		return 0;
+	}
+	ns_msg->header.version = priv->sb.version;
 	n = kernel_sendmsg(sock, &msg, &iov, 1, iov.iov_len);
 	if (n == -EAGAIN) {
 		xeth_mux_prepend_sbtxb(mux, sbtxb);
@@ -756,7 +763,11 @@ static int xeth_mux_sbrx(struct net_device *mux, struct socket *sock)
 	}
 	xeth_mux_inc_sbrx_msgs(mux);
 	err = xeth_sbrx_msg(mux, priv->sb.rx, n);
-	return err ? err : n;
+	if (err)
+		return err;
+	/* speak the version of the peer */
+	priv->sb.version = ((struct xeth_msg *)priv->sb.rx)->header.version;
+	return n;
 
 }
 
@@ -831,6 +842,7 @@ static int xeth_mux_main_exit(struct net_device *mux, struct socket *ln,
 static int xeth_mux_main(void *v)
 {
 	struct net_device *mux = v;
+	struct xeth_mux_priv *priv = netdev_priv(mux);
 	const int backlog = 128;
 	struct socket *ln = NULL, *conn;
 	struct sockaddr_un addr;
@@ -879,6 +891,7 @@ static int xeth_mux_main(void *v)
 			continue;
 		}
 		xeth_mux_set_sb_connection(mux);
+		priv->sb.version = XETH_MSG_VERSION;
 		xeth_mux_reset_all_link_stats(mux);
 		xeth_mux_reset_all_port_ethtool_stats(mux);
 		err = xeth_mux_service_sb(mux, conn);
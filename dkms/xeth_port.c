@@ -20,10 +20,21 @@ struct xeth_port_ext {
 	atomic64_t stats[xeth_mux_max_stats];
 };
 
+/* @xeth_port_eee: status sent by the switch daemon */
+struct xeth_port_eee {
+	u32 supported, advertised, lp_advertised;
+	u32 tx_lpi_timer;
+	bool eee_active, eee_enabled, tx_lpi_enabled;
+};
+
 struct xeth_port_priv {
 	struct xeth_proxy proxy;
 	int port, subport;
 	struct ethtool_link_ksettings ksettings;
+	/* @pause, @active_fec, @eee: negotiated by the switch daemon */
+	struct ethtool_pauseparam pause;
+	u32 active_fec;
+	struct xeth_port_eee eee;
 	/* @ext: only included w/ subport[0] */
 	struct xeth_port_ext ext[];
 };
@@ -113,6 +124,34 @@ void xeth_port_speed(struct net_device *nd, u32 mbps)
 	priv->ksettings.base.speed = mbps;
 }
 
+void xeth_port_pause(struct net_device *nd,
+		     struct xeth_msg_ethtool_pause *msg)
+{
+	struct xeth_port_priv *priv = netdev_priv(nd);
+	priv->pause.autoneg = msg->autoneg;
+	priv->pause.rx_pause = msg->rx_pause;
+	priv->pause.tx_pause = msg->tx_pause;
+}
+
+/* the XETH_FEC_*_BIT are those of ETHTOOL_FEC_* */
+void xeth_port_fec(struct net_device *nd, struct xeth_msg_ethtool_fec *msg)
+{
+	struct xeth_port_priv *priv = netdev_priv(nd);
+	priv->active_fec = msg->active_fec;
+}
+
+void xeth_port_eee(struct net_device *nd, struct xeth_msg_ethtool_eee *msg)
+{
+	struct xeth_port_priv *priv = netdev_priv(nd);
+	priv->eee.supported = msg->supported;
+	priv->eee.advertised = msg->advertised;
+	priv->eee.lp_advertised = msg->lp_advertised;
+	priv->eee.tx_lpi_timer = msg->tx_lpi_timer;
+	priv->eee.eee_active = msg->eee_active;
+	priv->eee.eee_enabled = msg->eee_enabled;
+	priv->eee.tx_lpi_enabled = msg->tx_lpi_enabled;
+}
+
 const struct net_device_ops xeth_port_ndo = {
 	.ndo_init = xeth_proxy_init,
 	.ndo_uninit = xeth_port_uninit,
@@ -436,6 +475,8 @@ static int xeth_port_get_fecparam(struct net_device *nd,
 			ETHTOOL_FEC_AUTO : ETHTOOL_FEC_RS;
 	else if (xeth_port_ks_advertising(ks, FEC_BASER))
 		param->fec = ETHTOOL_FEC_BASER;
+	if (priv->active_fec)
+		param->active_fec = priv->active_fec;
 	return 0;
 }
 
@@ -444,6 +485,8 @@ static int xeth_port_set_fecparam(struct net_device *nd,
 {
 	struct xeth_port_priv *priv = netdev_priv(nd);
 	struct ethtool_link_ksettings *ks = &priv->ksettings;
+	int err;
+
 	switch (param->fec) {
 	case ETHTOOL_FEC_AUTO:
 		if (!ethtool_link_ksettings_test_link_mode(ks, supported,
@@ -498,8 +541,89 @@ static int xeth_port_set_fecparam(struct net_device *nd,
 	default:
 		return -EINVAL;
 	}
-	return xeth_sbtx_et_settings(priv->proxy.mux, priv->proxy.xid, ks);
+	err = xeth_sbtx_et_settings(priv->proxy.mux, priv->proxy.xid, ks);
+	if (err)
+		return err;
+	return xeth_sbtx_ethtool_fec(priv->proxy.mux, priv->proxy.xid,
+				     param->fec);
+}
+
+static void xeth_port_get_pauseparam(struct net_device *nd,
+				     struct ethtool_pauseparam *param)
+{
+	struct xeth_port_priv *priv = netdev_priv(nd);
+	*param = priv->pause;
+}
+
+/* the switch daemon replies with the negotiated pause */
+static int xeth_port_set_pauseparam(struct net_device *nd,
+				    struct ethtool_pauseparam *param)
+{
+	struct xeth_port_priv *priv = netdev_priv(nd);
+	return xeth_sbtx_ethtool_pause(priv->proxy.mux, priv->proxy.xid,
+				       param);
+}
+
+#if defined(LINUX_VERSION_CODE) && \
+	(LINUX_VERSION_CODE >= KERNEL_VERSION(6, 9, 0))
+static int xeth_port_get_eee(struct net_device *nd, struct ethtool_keee *eee)
+{
+	struct xeth_port_priv *priv = netdev_priv(nd);
+
+	ethtool_convert_legacy_u32_to_link_mode(eee->supported,
+						priv->eee.supported);
+	ethtool_convert_legacy_u32_to_link_mode(eee->advertised,
+						priv->eee.advertised);
+	ethtool_convert_legacy_u32_to_link_mode(eee->lp_advertised,
+						priv->eee.lp_advertised);
+	eee->tx_lpi_timer = priv->eee.tx_lpi_timer;
+	eee->eee_active = priv->eee.eee_active;
+	eee->eee_enabled = priv->eee.eee_enabled;
+	eee->tx_lpi_enabled = priv->eee.tx_lpi_enabled;
+	return 0;
+}
+
+/* the switch daemon replies with the eee status */
+static int xeth_port_set_eee(struct net_device *nd, struct ethtool_keee *eee)
+{
+	struct xeth_port_priv *priv = netdev_priv(nd);
+	u32 advertised;
+
+	if (!ethtool_convert_link_mode_to_legacy_u32(&advertised,
+						     eee->advertised) ||
+	    (advertised & ~priv->eee.supported))
+		return -EINVAL;
+	return xeth_sbtx_ethtool_eee(priv->proxy.mux, priv->proxy.xid,
+				     advertised, eee->tx_lpi_timer,
+				     eee->eee_enabled, eee->tx_lpi_enabled);
+}
+#else
+static int xeth_port_get_eee(struct net_device *nd, struct ethtool_eee *eee)
+{
+	struct xeth_port_priv *priv = netdev_priv(nd);
+
+	eee->supported = priv->eee.supported;
+	eee->advertised = priv->eee.advertised;
+	eee->lp_advertised = priv->eee.lp_advertised;
+	eee->tx_lpi_timer = priv->eee.tx_lpi_timer;
+	eee->eee_active = priv->eee.eee_active;
+	eee->eee_enabled = priv->eee.eee_enabled;
+	eee->tx_lpi_enabled = priv->eee.tx_lpi_enabled;
+	return 0;
+}
+
+/* the switch daemon replies with the eee status */
+static int xeth_port_set_eee(struct net_device *nd, struct ethtool_eee *eee)
+{
+	struct xeth_port_priv *priv = netdev_priv(nd);
+
+	if (eee->advertised & ~priv->eee.supported)
+		return -EINVAL;
+	return xeth_sbtx_ethtool_eee(priv->proxy.mux, priv->proxy.xid,
+				     eee->advertised, eee->tx_lpi_timer,
+				     eee->eee_enabled, eee->tx_lpi_enabled);
 }
+#endif
 
 int xeth_port_get_module_info(struct net_device *nd,
 			      struct ethtool_modinfo *emi)
@@ -530,6 +654,10 @@ static const struct ethtool_ops xeth_port_eto = {
 	.set_link_ksettings = xeth_port_set_link_ksettings,
 	.get_fecparam = xeth_port_get_fecparam,
 	.set_fecparam = xeth_port_set_fecparam,
+	.get_pauseparam = xeth_port_get_pauseparam,
+	.set_pauseparam = xeth_port_set_pauseparam,
+	.get_eee = xeth_port_get_eee,
+	.set_eee = xeth_port_set_eee,
 	.get_module_info = xeth_port_get_module_info,
 	.get_module_eeprom = xeth_port_get_module_eeprom,
 };
@@ -539,6 +667,10 @@ static const struct ethtool_ops xeth_subport_eto = {
 	.get_link = ethtool_op_get_link,
 	.get_link_ksettings = xeth_port_get_link_ksettings,
 	.set_link_ksettings = xeth_port_set_link_ksettings,
+	.get_pauseparam = xeth_port_get_pauseparam,
+	.set_pauseparam = xeth_port_set_pauseparam,
+	.get_eee = xeth_port_get_eee,
+	.set_eee = xeth_port_set_eee,
 };
 
 static void xeth_port_setup(struct net_device *nd)
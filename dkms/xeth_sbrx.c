@@ -50,12 +50,43 @@ static void xeth_sbrx_speed(struct net_device *mux,
 		xeth_port_speed(proxy->nd, msg->mbps);
 }
 
+static void xeth_sbrx_pause(struct net_device *mux,
+			    struct xeth_msg_ethtool_pause *msg)
+{
+	struct xeth_proxy *proxy = xeth_mux_proxy_of_xid(mux, msg->xid);
+	if (proxy && proxy->kind == XETH_DEV_KIND_PORT)
+		xeth_port_pause(proxy->nd, msg);
+	else
+		xeth_mux_inc_sbrx_invalid(mux);
+}
+
+static void xeth_sbrx_fec(struct net_device *mux,
+			  struct xeth_msg_ethtool_fec *msg)
+{
+	struct xeth_proxy *proxy = xeth_mux_proxy_of_xid(mux, msg->xid);
+	if (proxy && proxy->kind == XETH_DEV_KIND_PORT)
+		xeth_port_fec(proxy->nd, msg);
+	else
+		xeth_mux_inc_sbrx_invalid(mux);
+}
+
+static void xeth_sbrx_eee(struct net_device *mux,
+			  struct xeth_msg_ethtool_eee *msg)
+{
+	struct xeth_proxy *proxy = xeth_mux_proxy_of_xid(mux, msg->xid);
+	if (proxy && proxy->kind == XETH_DEV_KIND_PORT)
+		xeth_port_eee(proxy->nd, msg);
+	else
+		xeth_mux_inc_sbrx_invalid(mux);
+}
+
 int xeth_sbrx_msg(struct net_device *mux, void *v, size_t n)
 {
 	struct xeth_msg_header *msg = v;
 	if (n < sizeof(*msg) || !xeth_sbrx_is_msg(msg))
 		return -EINVAL;
-	if (msg->version != XETH_MSG_VERSION)
+	if (!xeth_msg_version_match(msg) ||
+	    msg->version < xeth_msg_kind_version(msg->kind))
 		return -EINVAL;
 	switch (msg->kind) {
 	case XETH_MSG_KIND_DUMP_IFINFO:
@@ -81,6 +112,21 @@ int xeth_sbrx_msg(struct net_device *mux, void *v, size_t n)
 	case XETH_MSG_KIND_SPEED:
 		xeth_sbrx_speed(mux, v);
 		break;
+	case XETH_MSG_KIND_ETHTOOL_PAUSE:
+		if (n < sizeof(struct xeth_msg_ethtool_pause))
+			return -EINVAL;
+		xeth_sbrx_pause(mux, v);
+		break;
+	case XETH_MSG_KIND_ETHTOOL_FEC:
+		if (n < sizeof(struct xeth_msg_ethtool_fec))
+			return -EINVAL;
+		xeth_sbrx_fec(mux, v);
+		break;
+	case XETH_MSG_KIND_ETHTOOL_EEE:
+		if (n < sizeof(struct xeth_msg_ethtool_eee))
+			return -EINVAL;
+		xeth_sbrx_eee(mux, v);
+		break;
 	default:
 		xeth_mux_inc_sbrx_invalid(mux);
 		return -EINVAL;
@@ -127,6 +127,64 @@ int xeth_sbtx_et_settings(struct net_device *mux, u32 xid,
 				       ks->link_modes.advertising);
 }
 
+int xeth_sbtx_ethtool_pause(struct net_device *mux, u32 xid,
+			    const struct ethtool_pauseparam *param)
+{
+	struct xeth_sbtxb *sbtxb;
+	struct xeth_msg_ethtool_pause *msg;
+
+	sbtxb = xeth_mux_alloc_sbtxb(mux, sizeof(*msg));
+	if (!sbtxb)
+		return -ENOMEM;
+	msg = xeth_sbtxb_data(sbtxb);
+	xeth_sbtx_msg_set(msg, XETH_MSG_KIND_ETHTOOL_PAUSE);
+	msg->xid = xid;
+	msg->autoneg = param->autoneg ? 1 : 0;
+	msg->rx_pause = param->rx_pause ? 1 : 0;
+	msg->tx_pause = param->tx_pause ? 1 : 0;
+	xeth_mux_queue_sbtx(mux, sbtxb);
+	return 0;
+}
+
+/* the ETHTOOL_FEC_* bits are those of XETH_FEC_*_BIT */
+int xeth_sbtx_ethtool_fec(struct net_device *mux, u32 xid, u32 fec)
+{
+	struct xeth_sbtxb *sbtxb;
+	struct xeth_msg_ethtool_fec *msg;
+
+	sbtxb = xeth_mux_alloc_sbtxb(mux, sizeof(*msg));
+	if (!sbtxb)
+		return -ENOMEM;
+	msg = xeth_sbtxb_data(sbtxb);
+	xeth_sbtx_msg_set(msg, XETH_MSG_KIND_ETHTOOL_FEC);
+	msg->xid = xid;
+	msg->fec = fec;
+	xeth_mux_queue_sbtx(mux, sbtxb);
+	return 0;
+}
+
+/* @advertised: legacy link mode mask */
+int xeth_sbtx_ethtool_eee(struct net_device *mux, u32 xid, u32 advertised,
+			  u32 tx_lpi_timer, bool eee_enabled,
+			  bool tx_lpi_enabled)
+{
+	struct xeth_sbtxb *sbtxb;
+	struct xeth_msg_ethtool_eee *msg;
+
+	sbtxb = xeth_mux_alloc_sbtxb(mux, sizeof(*msg));
+	if (!sbtxb)
+		return -ENOMEM;
+	msg = xeth_sbtxb_data(sbtxb);
+	xeth_sbtx_msg_set(msg, XETH_MSG_KIND_ETHTOOL_EEE);
+	msg->xid = xid;
+	msg->advertised = advertised;
+	msg->tx_lpi_timer = tx_lpi_timer;
+	msg->eee_enabled = eee_enabled ? 1 : 0;
+	msg->tx_lpi_enabled = tx_lpi_enabled ? 1 : 0;
+	xeth_mux_queue_sbtx(mux, sbtxb);
+	return 0;
+}
+
 static const char * const xeth_sbtx_fib_event_names[] = {
 	[FIB_EVENT_ENTRY_REPLACE] "replace",
 	[FIB_EVENT_ENTRY_APPEND] "append",
@@ -70,8 +70,14 @@ static inline bool xeth_vlan_tci_is_exception(uint16_t tci)
 # define XETH_VLAN_TAG_PRESENT XETH_VLAN_CFI_MASK
 #endif
 
+/* Version 3 adds the ETHTOOL_PAUSE, ETHTOOL_FEC, and ETHTOOL_EEE kinds;
+ * otherwise its layouts are those of version 2. The driver sends the
+ * version last received and closes a session that sends a version that it
+ * doesn't speak.
+ */
 enum xeth_msg_version {
-	XETH_MSG_VERSION = 2,
+	XETH_MSG_MIN_VERSION = 2,
+	XETH_MSG_VERSION = 3,
 };
 
 enum {
@@ -148,6 +154,9 @@ enum xeth_msg_kind {
 	XETH_MSG_KIND_CHANGE_UPPER_XID,
 	XETH_MSG_KIND_NETNS_ADD,
 	XETH_MSG_KIND_NETNS_DEL,
+	XETH_MSG_KIND_ETHTOOL_PAUSE,
+	XETH_MSG_KIND_ETHTOOL_FEC,
+	XETH_MSG_KIND_ETHTOOL_EEE,
 };
 
 enum xeth_link_stat {
@@ -228,7 +237,14 @@ static inline enum xeth_msg_kind xeth_msg_kind(void *data)
 static inline bool xeth_msg_version_match(void *data)
 {
 	struct xeth_msg *msg = data;
-	return msg->header.version == XETH_MSG_VERSION;
+	return msg->header.version >= XETH_MSG_MIN_VERSION &&
+		msg->header.version <= XETH_MSG_VERSION;
+}
+
+/* the first version with the given kind */
+static inline uint8_t xeth_msg_kind_version(enum xeth_msg_kind kind)
+{
+	return kind >= XETH_MSG_KIND_ETHTOOL_PAUSE ? 3 : XETH_MSG_MIN_VERSION;
 }
 
 static inline void xeth_msg_init(void *data, enum xeth_msg_kind kind)
@@ -296,6 +312,54 @@ struct xeth_msg_ethtool_link_modes {
 	uint64_t modes;
 };
 
+/* from the driver, the ethtool -A request (set_pauseparam);
+ * to the driver, the negotiated pause
+ */
+struct xeth_msg_ethtool_pause {
+	struct xeth_msg_header header;
+	uint32_t xid;
+	uint8_t autoneg;
+	uint8_t rx_pause;
+	uint8_t tx_pause;
+	uint8_t pad;
+};
+
+enum xeth_fec_bit {
+	XETH_FEC_NONE_BIT,
+	XETH_FEC_AUTO_BIT,
+	XETH_FEC_OFF_BIT,
+	XETH_FEC_RS_BIT,
+	XETH_FEC_BASER_BIT,
+	XETH_FEC_LLRS_BIT,
+};
+
+/* from the driver, the ethtool --set-fec request (set_fecparam) w/o
+ * active_fec; to the driver, the configured and active fec
+ */
+struct xeth_msg_ethtool_fec {
+	struct xeth_msg_header header;
+	uint32_t xid;
+	uint32_t fec;		/* 1 << XETH_FEC_*_BIT */
+	uint32_t active_fec;
+	uint32_t reserved;
+};
+
+/* from the driver, the ethtool --set-eee request (set_eee) of advertised,
+ * tx_lpi_timer, eee_enabled, and tx_lpi_enabled; to the driver, the status
+ */
+struct xeth_msg_ethtool_eee {
+	struct xeth_msg_header header;
+	uint32_t xid;
+	uint32_t supported;	/* legacy link mode masks */
+	uint32_t advertised;
+	uint32_t lp_advertised;
+	uint32_t tx_lpi_timer;	/* usec */
+	uint8_t eee_active;
+	uint8_t eee_enabled;
+	uint8_t tx_lpi_enabled;
+	uint8_t pad;
+};
+
 struct xeth_next_hop {
 	int32_t ifindex;
 	int32_t weight;
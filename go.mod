@@ -1,3 +1,3 @@
 module github.com/platinasystems/xeth/v3
 
-go 1.16
+go 1.18
@@ -15,7 +15,10 @@ var (
 	flagDumpFib = flag.Bool("dump-fib", false, "dump fibinfo after ifinfo")
 	flagLog     = flag.String("log", "", "print to file instead of stdout")
 	flagLicense = flag.Bool("license", false, "print license and exit")
+	flagMetrics = flag.String("metrics", "", "serve OpenMetrics at address")
 	flagMux     = flag.String("mux", "xeth-mux", "netdev")
+	flagRecord  = flag.String("record", "", "capture messages to pcapng file")
+	flagRedial  = flag.Bool("redial", false, "reconnect after mux down")
 	flagVerbose = flag.Bool("verbose", false, "print xeth messages")
 )
 
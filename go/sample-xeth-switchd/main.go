@@ -5,18 +5,18 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 
 	"github.com/platinasystems/xeth/v3/go/xeth"
 )
 
 func main() {
-	var wg sync.WaitGroup
-	stopch := make(chan struct{})
-	sigch := make(chan os.Signal, 1)
 	xidOfDst := make(map[string]xeth.Xid)
 
 	if len(*flagLog) > 0 {
@@ -27,61 +27,77 @@ func main() {
 		return
 	}
 
-	signal.Notify(sigch,
+	ctx, stop := signal.NotifyContext(context.Background(),
 		syscall.SIGTERM,
 		syscall.SIGINT,
 		syscall.SIGHUP,
 		syscall.SIGQUIT)
-	defer signal.Stop(sigch)
+	defer stop()
 
-	task, err := xeth.Start(*flagMux, &wg, stopch)
+	var opts []xeth.TaskOption
+	if *flagRedial {
+		opts = append(opts, xeth.Reconnect(0, 0))
+	}
+	if len(*flagRecord) > 0 {
+		f, err := os.Create(*flagRecord)
+		if err != nil {
+			panic(err)
+		}
+		rec, err := xeth.NewRecorder(f)
+		if err != nil {
+			panic(err)
+		}
+		// after the task, write the buffered packets and close the file
+		defer rec.Close()
+		opts = append(opts, xeth.Record(rec))
+	}
+	task, err := xeth.StartContext(ctx, *flagMux, opts...)
 	if err != nil {
 		panic(err)
 	}
-	defer wg.Wait()
+	defer func() {
+		err := task.Close()
+		if err != nil && !errors.Is(err, io.EOF) {
+			panic(err)
+		}
+	}()
 
-	task.DumpIfInfo()
-selector:
-	for {
-		select {
-		case sig, ok := <-sigch:
-			if ok {
-				verbose("caught", sig)
+	if len(*flagMetrics) > 0 {
+		go http.ListenAndServe(*flagMetrics, xeth.MetricsHandler(task))
+	}
+
+	// a signal's cancel isn't an error
+	if err = task.DumpIfInfo(ctx); err != nil && ctx.Err() == nil {
+		panic(err)
+	}
+	for buf := range task.RxCh {
+		msg := xeth.Parse(buf)
+		verbose("<-", msg)
+		switch t := msg.(type) {
+		case xeth.Frame:
+			xid, found := xidOfDst[t.Dst().String()]
+			if found {
+				t.Xid(xid)
+				verbose("->", msg)
+				// t.Loopback(task)
 			}
-			close(stopch)
-			break selector
-		case <-task.Stop:
-			verbose("stopped")
-			break selector
-		case buf, ok := <-task.RxCh:
-			if !ok {
-				close(stopch)
-				if task.RxErr != nil {
-					panic(task.RxErr)
-				}
-				break selector
+		case xeth.Break:
+			for _, swept := range t.Swept {
+				verbose("swept", swept)
 			}
-			msg := xeth.Parse(buf)
-			verbose("<-", msg)
-			switch t := msg.(type) {
-			case xeth.Frame:
-				xid, found := xidOfDst[t.Dst().String()]
-				if found {
-					t.Xid(xid)
-					verbose("->", msg)
-					// t.Loopback(task)
-				}
-			case xeth.Break:
-				if *flagDumpFib {
-					*flagDumpFib = false
-					task.DumpFib()
+			if *flagDumpFib {
+				*flagDumpFib = false
+				err = task.DumpFib(ctx)
+				if err != nil && ctx.Err() == nil {
+					panic(err)
 				}
-			case xeth.DevNew:
-				xid := xeth.Xid(t)
-				ha := xeth.LinkOf(xid).IfInfoHardwareAddr()
-				xidOfDst[ha.String()] = xid
 			}
-			xeth.Pool(msg)
+		case xeth.DevNew:
+			xid := xeth.Xid(t)
+			ha := xeth.LinkOf(xid).IfInfoHardwareAddr()
+			xidOfDst[ha.String()] = xid
 		}
+		xeth.Pool(msg)
 	}
+	verbose("stopped", task.Err())
 }
@@ -0,0 +1,262 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// StatsAggregator sums the link stats of member ports into their LAG and
+// bridge uppers, which have no hardware counters, and sends the sums with
+// SetLinkStats. A member's stats count from its join, and those of a member
+// that quits remain in the upper's sum so that its counters don't decrease.
+// A VLAN mirrors the stats of its port or LAG since its traffic isn't
+// separable from theirs; so, as a member, it counts as that link, and only
+// once with it or other VLANs of it.
+//
+//	agg := xeth.NewStatsAggregator(task)
+//	agg.Subscribe(dispatcher)
+//	go agg.Run(ctx, time.Second)
+type StatsAggregator struct {
+	task   *Task
+	mutex  sync.Mutex
+	uppers map[Xid]*aggregate
+	dirty  map[Xid]struct{} // uppers with membership changes
+	wake   chan struct{}
+}
+
+type aggregate struct {
+	departed LinkStats // of quit members since their join
+	members  map[Xid]*aggregateMember
+}
+
+type aggregateMember struct {
+	base LinkStats // at join
+	last LinkStats
+}
+
+// limits the recursion of a membership loop
+const maxAggregateDepth = 8
+
+func NewStatsAggregator(task *Task) *StatsAggregator {
+	return &StatsAggregator{
+		task:   task,
+		uppers: make(map[Xid]*aggregate),
+		dirty:  make(map[Xid]struct{}),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Subscribe to the dispatcher's joins and quits so that Run recomputes the
+// sums of the changed uppers without waiting for its interval.
+func (a *StatsAggregator) Subscribe(d *Dispatcher) []*Subscription {
+	return []*Subscription{
+		d.OnDevJoin(a.join),
+		d.OnDevQuit(a.quit),
+	}
+}
+
+// Run Aggregate at the given interval, and Update with membership changes,
+// until the context is done or a send fails; returns the error that stopped
+// it.
+func (a *StatsAggregator) Run(ctx context.Context,
+	interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			err = a.Aggregate(ctx)
+		case <-a.wake:
+			err = a.Update(ctx)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Aggregate the member stats of each LAG and bridge, and those of each VLAN.
+func (a *StatsAggregator) Aggregate(ctx context.Context) (err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	LinkRange(func(xid Xid, l *Link) bool {
+		if l.IsLag() || l.IsBridge() || l.IsVlan() {
+			stats := a.stats(xid, 0)
+			err = a.task.SetLinkStats(ctx, xid, &stats)
+		}
+		return err == nil
+	})
+	for xid := range a.uppers {
+		if LinkOf(xid) == nil {
+			delete(a.uppers, xid)
+		}
+	}
+	a.dirty = make(map[Xid]struct{})
+	return
+}
+
+// Update the sums of the uppers with membership changes since the last
+// Aggregate or Update.
+func (a *StatsAggregator) Update(ctx context.Context) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for xid := range a.dirty {
+		delete(a.dirty, xid)
+		if LinkOf(xid) == nil {
+			continue
+		}
+		stats := a.stats(xid, 0)
+		if err := a.task.SetLinkStats(ctx, xid, &stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *StatsAggregator) join(join *DevJoin) {
+	a.mutex.Lock()
+	agg := a.aggregate(join.Upper)
+	lower := a.counted(join.Lower)
+	if _, found := agg.members[lower]; !found {
+		stats := a.stats(lower, 0)
+		agg.members[lower] = &aggregateMember{stats, stats}
+	}
+	a.touch(join.Upper, 0)
+	a.mutex.Unlock()
+	a.signal()
+}
+
+// fold the increase since the last sum of the departed member into the
+// upper before its counters move on without it
+func (a *StatsAggregator) quit(quit *DevQuit) {
+	a.mutex.Lock()
+	if l := LinkOf(quit.Upper); l != nil && (l.IsLag() || l.IsBridge()) {
+		a.sum(quit.Upper, l, 1)
+	}
+	a.touch(quit.Upper, 0)
+	a.mutex.Unlock()
+	a.signal()
+}
+
+// mark the upper and those above it for Update
+func (a *StatsAggregator) touch(upper Xid, depth int) {
+	l := LinkOf(upper)
+	if l == nil || depth > maxAggregateDepth {
+		return
+	}
+	if l.IsLag() || l.IsBridge() {
+		a.dirty[upper] = struct{}{}
+	}
+	for _, xid := range l.Uppers() {
+		a.touch(xid, depth+1)
+	}
+}
+
+// wake Run
+func (a *StatsAggregator) signal() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (a *StatsAggregator) aggregate(upper Xid) *aggregate {
+	agg := a.uppers[upper]
+	if agg == nil {
+		agg = &aggregate{members: make(map[Xid]*aggregateMember)}
+		a.uppers[upper] = agg
+	}
+	return agg
+}
+
+// stats of a port, the sum of a LAG or bridge, or those of a VLAN's link
+func (a *StatsAggregator) stats(xid Xid, depth int) (stats LinkStats) {
+	l := LinkOf(xid)
+	if l == nil || depth > maxAggregateDepth {
+		return
+	}
+	switch {
+	case l.IsPort():
+		stats = l.LinkStats()
+	case l.IsLag(), l.IsBridge():
+		stats = a.sum(xid, l, depth+1)
+	case l.IsVlan():
+		stats = a.stats(vlanLink(xid, l), depth+1)
+	}
+	return
+}
+
+// the port or LAG of a VLAN, which its xid encodes below the VID
+func vlanLink(xid Xid, l *Link) Xid {
+	if l.IfInfoKdata() == EncapVpls {
+		return xid & EncapVplsVidMask
+	}
+	return xid & EncapVlanVidMask
+}
+
+// the link whose stats a member adds to a sum; a VLAN counts as its link
+func (a *StatsAggregator) counted(xid Xid) Xid {
+	for depth := 0; depth <= maxAggregateDepth; depth++ {
+		l := LinkOf(xid)
+		if l == nil || !l.IsVlan() {
+			break
+		}
+		xid = vlanLink(xid, l)
+	}
+	return xid
+}
+
+// sum the stats of the upper's members since their join with those of the
+// departed; members present before the first sum count from zero.
+func (a *StatsAggregator) sum(upper Xid, l *Link, depth int) LinkStats {
+	agg := a.aggregate(upper)
+	var lowers []Xid
+	for _, xid := range l.Lowers() {
+		lowers = a.counted(xid).List(lowers)
+	}
+	for xid, m := range agg.members {
+		if !xid.in(lowers) {
+			// with the final increase of a member that remains
+			if LinkOf(xid) != nil {
+				m.last = a.stats(xid, depth)
+			}
+			addDelta(&agg.departed, &m.base, &m.last)
+			delete(agg.members, xid)
+		}
+	}
+	total := agg.departed
+	for _, xid := range lowers {
+		m := agg.members[xid]
+		if m == nil {
+			m = new(aggregateMember)
+			agg.members[xid] = m
+		}
+		m.last = a.stats(xid, depth)
+		addDelta(&total, &m.base, &m.last)
+	}
+	return total
+}
+
+func (xid Xid) in(xids []Xid) bool {
+	for _, entry := range xids {
+		if entry == xid {
+			return true
+		}
+	}
+	return false
+}
+
+func addDelta(total, base, last *LinkStats) {
+	t, b := total.Array(), base.Array()
+	for i, n := range last.Array() {
+		t[i] += counterDelta(b[i], n)
+	}
+}
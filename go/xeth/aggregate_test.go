@@ -0,0 +1,106 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/xethtest"
+)
+
+func TestStatsAggregator(t *testing.T) {
+	mux, task := startTask(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const port1, port2, lag = 101, 102, 103
+	const vlan = port1 | 5<<xeth.EncapVlanVidBit
+	const stat = xeth.LinkStatRxPackets
+	mux.IfInfo(
+		xethtest.MsgIfInfo(port1, "xeth101", 101, xeth.DevKindPort,
+			xethtest.ReasonDump, testHa),
+		xethtest.MsgIfInfo(port2, "xeth102", 102, xeth.DevKindPort,
+			xethtest.ReasonDump, testHa),
+		xethtest.MsgIfInfo(lag, "xeth103", 103, xeth.DevKindLag,
+			xethtest.ReasonDump, testHa),
+		xethtest.MsgIfInfo(vlan, "xeth101.5", 104, xeth.DevKindVlan,
+			xethtest.ReasonDump, testHa))
+	if err := task.DumpIfInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	untilBreak(t, task)
+
+	d := xeth.NewDispatcher()
+	agg := xeth.NewStatsAggregator(task)
+	agg.Subscribe(d)
+	changed := make(chan struct{}, 4)
+	d.OnDevJoin(func(*xeth.DevJoin) { changed <- struct{}{} })
+	d.OnDevQuit(func(*xeth.DevQuit) { changed <- struct{}{} })
+	go d.Run(ctx, task)
+	// sum with membership changes and each expect, but not between
+	go agg.Run(ctx, time.Hour)
+
+	set := func(xid xeth.Xid, n uint64) {
+		t.Helper()
+		if err := task.SetLinkStat(ctx, xid, stat, n); err != nil {
+			t.Fatal(err)
+		}
+		eventually(t, func() bool {
+			return xeth.LinkOf(xid).LinkStats().RxPackets == n
+		})
+	}
+	expect := func(xid xeth.Xid, n uint64) {
+		t.Helper()
+		if err := agg.Aggregate(ctx); err != nil {
+			t.Fatal(err)
+		}
+		eventually(t, func() bool {
+			got, _ := mux.LinkStat(xid, stat)
+			return got == n
+		})
+	}
+	send := func(msgs ...[]byte) {
+		t.Helper()
+		if err := mux.Send(msgs...); err != nil {
+			t.Fatal(err)
+		}
+		for range msgs {
+			select {
+			case <-changed:
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout")
+			}
+		}
+	}
+	set(port1, 10)
+	set(port2, 20)
+	expect(vlan, 10)
+	// members count from their join
+	send(xethtest.MsgChangeUpperXid(lag, port1, true),
+		xethtest.MsgChangeUpperXid(lag, port2, true))
+	set(port1, 15)
+	set(port2, 27)
+	expect(lag, 12)
+	expect(vlan, 15)
+	// and those of a member that quits remain
+	send(xethtest.MsgChangeUpperXid(lag, port2, false))
+	set(port2, 100)
+	set(port1, 16)
+	expect(lag, 13)
+	// a VLAN member counts as its port, once
+	send(xethtest.MsgChangeUpperXid(lag, vlan, true))
+	set(port1, 20)
+	expect(lag, 17)
+	send(xethtest.MsgChangeUpperXid(lag, vlan, false))
+	set(port1, 21)
+	expect(lag, 18)
+	// with the increase since the last sum of a member that quits
+	set(port1, 25)
+	send(xethtest.MsgChangeUpperXid(lag, port1, false))
+	set(port1, 100)
+	expect(lag, 22)
+}
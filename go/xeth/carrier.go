@@ -0,0 +1,350 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/platinasystems/xeth/v3/go/endian"
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// CarrierDampening delays and suppresses the carrier changes of SetCarrier.
+//
+// A change is sent after the carrier holds for UpHold or DownHold; a change
+// that reverts within the hold isn't sent. Each carrier loss adds Penalty
+// to the link's flap penalty, which halves every HalfLife. While the penalty
+// exceeds Suppress, changes aren't sent until it decays below Reuse or the
+// link has been suppressed for MaxSuppress; then the latest carrier is sent.
+// A zero HalfLife disables flap suppression.
+type CarrierDampening struct {
+	UpHold      time.Duration
+	DownHold    time.Duration
+	HalfLife    time.Duration
+	Penalty     float64
+	Suppress    float64
+	Reuse       float64
+	MaxSuppress time.Duration
+}
+
+// DefaultCarrierDampening is like the interface dampening of routers.
+var DefaultCarrierDampening = CarrierDampening{
+	HalfLife:    5 * time.Second,
+	Penalty:     1000,
+	Suppress:    2000,
+	Reuse:       1000,
+	MaxSuppress: 20 * time.Second,
+}
+
+// CarrierDampened notes the start and end of a link's flap suppression with
+// the total number of carrier changes not sent to the driver for the link.
+type CarrierDampened struct {
+	Xid
+	Suppressed  bool
+	Transitions uint64
+}
+
+// Carrier changes and notes are decided with the mutex held and sent after
+// it's released so that a full hi-priority channel doesn't stall the other
+// links. Notes are queued only until closed so that goClose may close RxCh.
+type dampening struct {
+	CarrierDampening
+	mutex  sync.Mutex
+	xids   map[Xid]*dampened
+	closed bool
+}
+
+type dampened struct {
+	sent, want bool // sent is that of the latest send, even if pending
+	penalty    float64
+	decayed    time.Time // of penalty
+	suppressed time.Time // or zero
+	timer      *time.Timer
+	count      uint64 // suppressed transitions
+	seq        uint64 // of the latest send
+	sending    sync.Mutex
+}
+
+// The carrier to send and the notes to queue after a decision.
+type dampedOut struct {
+	xid   Xid
+	x     *dampened
+	send  bool
+	on    bool
+	prev  bool // sent before, restored if the send fails
+	first bool
+	seq   uint64
+	notes []CarrierDampened
+}
+
+const sizeofMsgCarrierDampened = internal.SizeofMsg + 16
+
+// DampenCarrier delays and suppresses the carrier changes of SetCarrier.
+func DampenCarrier(d CarrierDampening) TaskOption {
+	return func(task *Task) {
+		task.dampening = &dampening{
+			CarrierDampening: d,
+			xids:             make(map[Xid]*dampened),
+		}
+	}
+}
+
+// CarrierSuppressed returns the number of carrier changes of xid that
+// weren't sent to the driver.
+func (task *Task) CarrierSuppressed(xid Xid) uint64 {
+	d := task.dampening
+	if d == nil {
+		return 0
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if x := d.xids[xid]; x != nil {
+		return x.count
+	}
+	return 0
+}
+
+// Send carrier change to driver through hi-priority channel after any
+// dampening.
+func (task *Task) SetCarrier(ctx context.Context, xid Xid, on bool) error {
+	d := task.dampening
+	if d == nil {
+		return task.sendCarrier(ctx, xid, on)
+	}
+	if err := task.stopped(ctx); err != nil {
+		return err
+	}
+	now := time.Now()
+	d.mutex.Lock()
+	if d.closed {
+		d.mutex.Unlock()
+		return task.stopped(ctx)
+	}
+	out := d.set(task, xid, on, now)
+	d.mutex.Unlock()
+	return d.flush(ctx, task, out)
+}
+
+// decide the carrier change with mutex held
+func (d *dampening) set(task *Task, xid Xid, on bool,
+	now time.Time) *dampedOut {
+	out := &dampedOut{xid: xid}
+	x := d.xids[xid]
+	if x == nil {
+		// send the first without delay
+		x = &dampened{sent: !on, want: on, decayed: now}
+		d.xids[xid] = x
+		out.first = true
+		out.carrier(x, on)
+		return out
+	}
+	if on == x.want {
+		return out
+	}
+	x.want = on
+	d.decay(x, now)
+	if !on && d.HalfLife > 0 {
+		x.penalty += d.Penalty
+	}
+	if x.suppressed.IsZero() && d.HalfLife > 0 && x.penalty > d.Suppress {
+		x.suppressed = now
+		out.notes = append(out.notes,
+			CarrierDampened{xid, true, x.count})
+	}
+	switch {
+	case !x.suppressed.IsZero():
+		x.count++
+		task.metrics.CarrierSuppressed.Inc()
+		d.schedule(task, xid, x, d.reuse(x))
+	case on == x.sent:
+		// reverted within hold
+		x.count++
+		task.metrics.CarrierSuppressed.Inc()
+		x.stop()
+	case on && d.UpHold > 0:
+		d.schedule(task, xid, x, d.UpHold)
+	case !on && d.DownHold > 0:
+		d.schedule(task, xid, x, d.DownHold)
+	default:
+		x.stop()
+		out.carrier(x, on)
+	}
+	return out
+}
+
+// mark the carrier sent, with mutex held, before its send
+func (out *dampedOut) carrier(x *dampened, on bool) {
+	x.seq++
+	out.x, out.send, out.on, out.prev, out.seq = x, true, on, x.sent, x.seq
+	x.sent = on
+}
+
+// Queue the notes then send the carrier without the mutex; the sends of a
+// link are in order, and one superseded by a later decision isn't sent.
+func (d *dampening) flush(ctx context.Context, task *Task,
+	out *dampedOut) error {
+	if len(out.notes) > 0 {
+		d.mutex.Lock()
+		if !d.closed {
+			for i := range out.notes {
+				task.noteCarrierDampened(&out.notes[i])
+			}
+		}
+		d.mutex.Unlock()
+	}
+	if !out.send {
+		return nil
+	}
+	x := out.x
+	x.sending.Lock()
+	defer x.sending.Unlock()
+	d.mutex.Lock()
+	superseded := x.seq != out.seq
+	d.mutex.Unlock()
+	if superseded {
+		return nil
+	}
+	err := task.sendCarrier(ctx, out.xid, out.on)
+	if err != nil {
+		d.mutex.Lock()
+		if x.seq == out.seq {
+			// unchanged, so that it may be set again
+			if out.first {
+				delete(d.xids, out.xid)
+			}
+			x.sent, x.want = out.prev, out.prev
+		}
+		d.mutex.Unlock()
+	}
+	return err
+}
+
+func (task *Task) sendCarrier(ctx context.Context, xid Xid, on bool) error {
+	buf := newBuffer(internal.SizeofMsgCarrier)
+	msg := (*internal.MsgCarrier)(buf.pointer())
+	msg.Header.Set(internal.MsgKindCarrier)
+	msg.Xid = uint32(xid)
+	if on {
+		msg.Flag = internal.CarrierOn
+	} else {
+		msg.Flag = internal.CarrierOff
+	}
+	return task.hi(ctx, buf)
+}
+
+// decay penalty with mutex held
+func (d *dampening) decay(x *dampened, now time.Time) {
+	if d.HalfLife > 0 && x.penalty > 0 {
+		halves := float64(now.Sub(x.decayed)) / float64(d.HalfLife)
+		x.penalty *= math.Exp2(-halves)
+	}
+	x.decayed = now
+}
+
+// time until a suppressed penalty decays to reuse, within max suppress
+func (d *dampening) reuse(x *dampened) time.Duration {
+	wait := time.Duration(0)
+	if x.penalty > d.Reuse && d.Reuse > 0 {
+		wait = time.Duration(float64(d.HalfLife) *
+			math.Log2(x.penalty/d.Reuse))
+	}
+	if d.MaxSuppress > 0 {
+		if left := d.MaxSuppress - x.decayed.Sub(x.suppressed); wait > left {
+			wait = left
+		}
+	}
+	return wait
+}
+
+// (re)start the timer with mutex held
+func (d *dampening) schedule(task *Task, xid Xid, x *dampened,
+	after time.Duration) {
+	x.stop()
+	x.timer = time.AfterFunc(after, func() { d.expire(task, xid) })
+}
+
+func (x *dampened) stop() {
+	if x.timer != nil {
+		x.timer.Stop()
+		x.timer = nil
+	}
+}
+
+// send the wanted carrier after a hold or suppression
+func (d *dampening) expire(task *Task, xid Xid) {
+	now := time.Now()
+	d.mutex.Lock()
+	x := d.xids[xid]
+	if d.closed || x == nil {
+		d.mutex.Unlock()
+		return
+	}
+	x.timer = nil
+	out := &dampedOut{xid: xid}
+	if !x.suppressed.IsZero() {
+		d.decay(x, now)
+		if wait := d.reuse(x); wait > 0 {
+			d.schedule(task, xid, x, wait)
+			d.mutex.Unlock()
+			return
+		}
+		x.suppressed = time.Time{}
+		out.notes = append(out.notes,
+			CarrierDampened{xid, false, x.count})
+	}
+	if x.want != x.sent {
+		out.carrier(x, x.want)
+	}
+	d.mutex.Unlock()
+	d.flush(task.ctx, task, out)
+}
+
+// stop the timers before goClose closes RxCh
+func (d *dampening) close() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.closed = true
+	for _, x := range d.xids {
+		x.stop()
+	}
+}
+
+// queue the note, with mutex held and before close, unless RxCh is full
+func (task *Task) noteCarrierDampened(note *CarrierDampened) {
+	buf := newBuffer(sizeofMsgCarrierDampened)
+	h := (*internal.MsgHeader)(buf.pointer())
+	h.SetLocal(internal.MsgKindCarrierDampened)
+	b := buf.bytes()[internal.SizeofMsg:]
+	endian.Host.PutUint32(b, uint32(note.Xid))
+	endian.Host.PutUint32(b[4:], 0)
+	if note.Suppressed {
+		b[4] = 1
+	}
+	endian.Host.PutUint64(b[8:], note.Transitions)
+	if task.rec != nil {
+		task.rec.Msg(buf.bytes(), DirectionIn)
+	}
+	select {
+	case task.rxch <- buf:
+	default:
+		task.drop(buf)
+	}
+}
+
+func rxCarrierDampened(buf buffer) CarrierDampened {
+	b := buf.bytes()
+	if len(b) < sizeofMsgCarrierDampened {
+		return CarrierDampened{}
+	}
+	b = b[internal.SizeofMsg:]
+	return CarrierDampened{
+		Xid:         Xid(endian.Host.Uint32(b)),
+		Suppressed:  b[4] != 0,
+		Transitions: endian.Host.Uint64(b[8:]),
+	}
+}
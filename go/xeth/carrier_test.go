@@ -0,0 +1,105 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+	"github.com/platinasystems/xeth/v3/go/xeth/xethtest"
+)
+
+func TestCarrierHold(t *testing.T) {
+	mux, task := startTask(t, xeth.DampenCarrier(xeth.CarrierDampening{
+		DownHold: 50 * time.Millisecond,
+	}))
+	ctx := context.Background()
+	const xid = 111
+	set := func(on bool) {
+		t.Helper()
+		if err := task.SetCarrier(ctx, xid, on); err != nil {
+			t.Fatal(err)
+		}
+	}
+	set(true)
+	eventually(t, func() bool {
+		on, _ := mux.Carrier(xid)
+		return on
+	})
+	// reverted within hold
+	set(false)
+	set(true)
+	time.Sleep(100 * time.Millisecond)
+	if n := carriers(mux); n != 1 {
+		t.Error("sent", n)
+	}
+	if n := task.CarrierSuppressed(xid); n != 1 {
+		t.Error("suppressed", n)
+	}
+	set(false)
+	eventually(t, func() bool {
+		on, found := mux.Carrier(xid)
+		return found && !on
+	})
+	// close with a pending hold
+	set(true)
+	set(false)
+	if err := task.Close(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+}
+
+func TestCarrierSuppress(t *testing.T) {
+	mux, task := startTask(t, xeth.DampenCarrier(xeth.CarrierDampening{
+		HalfLife:    time.Minute,
+		Penalty:     1000,
+		Suppress:    1500,
+		Reuse:       1000,
+		MaxSuppress: 50 * time.Millisecond,
+	}))
+	ctx := context.Background()
+	const xid = 112
+	for _, on := range []bool{true, false, true, false, true} {
+		if err := task.SetCarrier(ctx, xid, on); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var notes []xeth.CarrierDampened
+	for len(notes) < 2 {
+		if note, ok := next(t, task).(xeth.CarrierDampened); ok {
+			notes = append(notes, note)
+		}
+	}
+	if !notes[0].Suppressed || notes[0].Xid != xid {
+		t.Error("start", notes[0])
+	}
+	if notes[1].Suppressed || notes[1].Transitions != 2 {
+		t.Error("end", notes[1])
+	}
+	// the latest carrier is sent after suppression
+	eventually(t, func() bool {
+		on, _ := mux.Carrier(xid)
+		return on
+	})
+	if n := carriers(mux); n != 3 {
+		t.Error("sent", n)
+	}
+}
+
+// number of carrier messages received by the mux
+func carriers(mux *xethtest.Mux) (n int) {
+	for _, b := range mux.Received() {
+		h := (*internal.MsgHeader)(unsafe.Pointer(&b[0]))
+		if h.Kind == internal.MsgKindCarrier {
+			n++
+		}
+	}
+	return
+}
@@ -0,0 +1,306 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This package encodes and decodes the side-band messages of xeth_uapi.h
+// without unsafe casts so that tools, simulators, and tests may build and
+// inspect any message in either direction.
+//
+//	b, err := codec.Marshal(&codec.Carrier{Xid: 3, Flag: codec.CarrierOn})
+//	...
+//	msg, err := codec.Unmarshal(b)
+//	if carrier, ok := msg.(*codec.Carrier); ok {
+//		...
+//	}
+//
+// Integer fields are in host byte order like the driver; addresses that
+// the driver defines as __be32 are network order IPs.
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/platinasystems/xeth/v3/go/endian"
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// Msg is implemented by a pointer to each message type.
+type Msg interface {
+	Kind() xeth.MsgKind
+	// Len is the encoded length including any variable length arrays.
+	Len() int
+	encode(e *encoder) error
+	decode(d *decoder)
+}
+
+// Error describes a message that couldn't be encoded or decoded; Err may be
+// or wrap one of ErrShort, ErrHeader, ErrVersion, or ErrKind.
+type Error struct {
+	Kind xeth.MsgKind
+	Len  int
+	Err  error
+}
+
+var (
+	ErrShort   = errors.New("short message")
+	ErrHeader  = errors.New("non-zero header fields")
+	ErrVersion = errors.New("unsupported version")
+	ErrKind    = errors.New("unsupported kind")
+)
+
+// Version of the encoded messages; Unmarshal decodes MinVersion through
+// Version, less the kinds newer than the message's version.
+const (
+	Version    = internal.MsgVersion
+	MinVersion = internal.MinMsgVersion
+)
+
+const sizeofHeader = internal.SizeofMsg
+
+// Marshal returns the encoded message.
+func Marshal(msg Msg) ([]byte, error) {
+	b := make([]byte, msg.Len())
+	if _, err := MarshalTo(b, msg); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// MarshalTo encodes the message to the given buffer and returns its length.
+func MarshalTo(b []byte, msg Msg) (int, error) {
+	n := msg.Len()
+	if len(b) < n {
+		return 0, &Error{msg.Kind(), len(b), ErrShort}
+	}
+	e := &encoder{b: b[:n]}
+	e.zero(sizeofHeader - 2)
+	e.u8(Version)
+	e.u8(uint8(msg.Kind()))
+	if err := msg.encode(e); err != nil {
+		return 0, &Error{msg.Kind(), n, err}
+	}
+	return n, nil
+}
+
+// Unmarshal returns a new message decoded from the given buffer.
+// Trailing bytes beyond the message length are ignored, except that each
+// whole word following a link modes message widens its modes.
+func Unmarshal(b []byte) (Msg, error) {
+	if len(b) < sizeofHeader {
+		return nil, &Error{0, len(b), ErrShort}
+	}
+	kind := xeth.MsgKind(b[sizeofHeader-1])
+	for _, c := range b[:sizeofHeader-2] {
+		if c != 0 {
+			return nil, &Error{kind, len(b), ErrHeader}
+		}
+	}
+	if version := b[sizeofHeader-2]; version < MinVersion ||
+		version > Version ||
+		version < internal.KindVersion(uint8(kind)) {
+		return nil, &Error{kind, len(b),
+			fmt.Errorf("%w %d", ErrVersion, version)}
+	}
+	msg := New(kind)
+	if msg == nil {
+		return nil, &Error{kind, len(b), ErrKind}
+	}
+	d := &decoder{b: b, off: sizeofHeader}
+	msg.decode(d)
+	if d.err != nil {
+		return nil, &Error{kind, len(b), d.err}
+	}
+	return msg, nil
+}
+
+// New returns an empty message of the given kind, or nil if unsupported.
+func New(kind xeth.MsgKind) Msg {
+	switch kind {
+	case xeth.MsgKindBreak:
+		return new(Break)
+	case xeth.MsgKindLinkStat, xeth.MsgKindEthtoolStat:
+		return &Stat{MsgKind: kind}
+	case xeth.MsgKindEthtoolFlags:
+		return new(EthtoolFlags)
+	case xeth.MsgKindEthtoolSettings:
+		return new(EthtoolSettings)
+	case xeth.MsgKindEthtoolLinkModesSupported,
+		xeth.MsgKindEthtoolLinkModesAdvertising,
+		xeth.MsgKindEthtoolLinkModesLPAdvertising:
+		return &EthtoolLinkModes{MsgKind: kind}
+	case xeth.MsgKindDumpIfInfo:
+		return new(DumpIfInfo)
+	case xeth.MsgKindCarrier:
+		return new(Carrier)
+	case xeth.MsgKindSpeed:
+		return new(Speed)
+	case xeth.MsgKindIfInfo:
+		return new(IfInfo)
+	case xeth.MsgKindIfa:
+		return new(Ifa)
+	case xeth.MsgKindIfa6:
+		return new(Ifa6)
+	case xeth.MsgKindDumpFibInfo:
+		return new(DumpFibInfo)
+	case xeth.MsgKindFibEntry:
+		return new(FibEntry)
+	case xeth.MsgKindFib6Entry:
+		return new(Fib6Entry)
+	case xeth.MsgKindNeighUpdate:
+		return new(NeighUpdate)
+	case xeth.MsgKindChangeUpperXid:
+		return new(ChangeUpperXid)
+	case xeth.MsgKindNetNsAdd, xeth.MsgKindNetNsDel:
+		return &NetNs{MsgKind: kind}
+	case xeth.MsgKindEthtoolPause:
+		return new(EthtoolPause)
+	case xeth.MsgKindEthtoolFec:
+		return new(EthtoolFec)
+	case xeth.MsgKindEthtoolEee:
+		return new(EthtoolEee)
+	}
+	return nil
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("xeth %v msg, length %d: %v",
+		err.Kind, err.Len, err.Err)
+}
+
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+type encoder struct {
+	b   []byte
+	off int
+}
+
+func (e *encoder) u8(v uint8) {
+	e.b[e.off] = v
+	e.off++
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.u8(1)
+	} else {
+		e.u8(0)
+	}
+}
+
+func (e *encoder) u32(v uint32) {
+	endian.Host.PutUint32(e.b[e.off:], v)
+	e.off += 4
+}
+
+func (e *encoder) u64(v uint64) {
+	endian.Host.PutUint64(e.b[e.off:], v)
+	e.off += 8
+}
+
+// copy up to n bytes then zero fill
+func (e *encoder) bytes(v []byte, n int) {
+	copy(e.b[e.off:e.off+n], v)
+	for i := len(v); i < n; i++ {
+		e.b[e.off+i] = 0
+	}
+	e.off += n
+}
+
+func (e *encoder) zero(n int) {
+	e.bytes(nil, n)
+}
+
+// an IPv4 address in network order or zero if nil
+func (e *encoder) ip4(ip net.IP) error {
+	if len(ip) == 0 {
+		e.zero(net.IPv4len)
+		return nil
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return fmt.Errorf("%v isn't IPv4", ip)
+	}
+	e.bytes(ip4, net.IPv4len)
+	return nil
+}
+
+func (e *encoder) ip6(ip net.IP) error {
+	if len(ip) == 0 {
+		e.zero(net.IPv6len)
+		return nil
+	}
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return fmt.Errorf("%v isn't an IP", ip)
+	}
+	e.bytes(ip16, net.IPv6len)
+	return nil
+}
+
+type decoder struct {
+	b   []byte
+	off int
+	err error
+}
+
+// need n more bytes
+func (d *decoder) need(n int) bool {
+	if d.err != nil {
+		return false
+	}
+	if d.off+n > len(d.b) {
+		d.err = fmt.Errorf("%w, need %d at %d", ErrShort, n, d.off)
+		return false
+	}
+	return true
+}
+
+func (d *decoder) u8() (v uint8) {
+	if d.need(1) {
+		v = d.b[d.off]
+		d.off++
+	}
+	return
+}
+
+func (d *decoder) bool() bool {
+	return d.u8() != 0
+}
+
+func (d *decoder) u32() (v uint32) {
+	if d.need(4) {
+		v = endian.Host.Uint32(d.b[d.off:])
+		d.off += 4
+	}
+	return
+}
+
+func (d *decoder) u64() (v uint64) {
+	if d.need(8) {
+		v = endian.Host.Uint64(d.b[d.off:])
+		d.off += 8
+	}
+	return
+}
+
+// copy of next n bytes
+func (d *decoder) bytes(n int) []byte {
+	if !d.need(n) {
+		return nil
+	}
+	v := make([]byte, n)
+	copy(v, d.b[d.off:])
+	d.off += n
+	return v
+}
+
+func (d *decoder) skip(n int) {
+	if d.need(n) {
+		d.off += n
+	}
+}
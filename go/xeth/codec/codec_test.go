@@ -0,0 +1,353 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+var (
+	testIP4  = net.IPv4(10, 1, 2, 3).To4()
+	testMask = net.CIDRMask(24, 32)
+	testIP6  = net.ParseIP("2001:db8::1")
+	testGw6  = net.ParseIP("fe80::2")
+	testHa   = net.HardwareAddr{2, 0, 0, 0, 0, 1}
+)
+
+// copy an address to a __be32 or byte array field
+func put(p unsafe.Pointer, b []byte) {
+	copy((*[net.IPv6len]byte)(p)[:len(b)], b)
+}
+
+var testMsgs = []struct {
+	msg Msg
+	// fill the internal struct of the expected encoding
+	want func(p unsafe.Pointer)
+}{
+	{&Break{}, func(unsafe.Pointer) {}},
+	{&DumpIfInfo{}, func(unsafe.Pointer) {}},
+	{&DumpFibInfo{}, func(unsafe.Pointer) {}},
+	{&Carrier{Xid: 3, Flag: CarrierOn}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgCarrier)(p)
+		msg.Xid = 3
+		msg.Flag = internal.CarrierOn
+	}},
+	{&ChangeUpperXid{Upper: 4, Lower: 5, Linking: true},
+		func(p unsafe.Pointer) {
+			msg := (*internal.MsgChangeUpperXid)(p)
+			msg.Upper = 4
+			msg.Lower = 5
+			msg.Linking = 1
+		}},
+	{&EthtoolFlags{Xid: 6, Flags: 0x5}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgEthtoolFlags)(p)
+		msg.Xid = 6
+		msg.Flags = 0x5
+	}},
+	{&EthtoolSettings{
+		Xid:           7,
+		Speed:         100000,
+		Duplex:        1,
+		Port:          2,
+		PhyAddress:    3,
+		Autoneg:       1,
+		MdioSupport:   4,
+		EthTpMdix:     5,
+		EthTpMdixCtrl: 6,
+	}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgEthtoolSettings)(p)
+		msg.Xid = 7
+		msg.Speed = 100000
+		msg.Duplex = 1
+		msg.Port = 2
+		msg.Phy_address = 3
+		msg.Autoneg = 1
+		msg.Mdio_support = 4
+		msg.Eth_tp_mdix = 5
+		msg.Eth_tp_mdix_ctrl = 6
+	}},
+	{&EthtoolLinkModes{
+		MsgKind: xeth.MsgKindEthtoolLinkModesAdvertising,
+		Xid:     8,
+		Modes:   xeth.EthtoolLinkModeBits{0x11, 0x22},
+	}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgEthtoolLinkModes)(p)
+		msg.Xid = 8
+		msg.Modes = 0x11
+		// the second word follows the first
+		*(*uint64)(unsafe.Pointer(uintptr(p) +
+			internal.SizeofMsgEthtoolLinkModes)) = 0x22
+	}},
+	{&FibEntry{
+		Net:     9,
+		Address: testIP4,
+		Mask:    testMask,
+		Event:   1,
+		Tos:     2,
+		Type:    3,
+		Table:   254,
+		NextHops: []NextHop{
+			{Ifindex: 10, Weight: 1, Flags: 4, Gw: testIP4, Scope: 5},
+		},
+	}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgFibEntry)(p)
+		msg.Net = 9
+		put(unsafe.Pointer(&msg.Address), testIP4)
+		put(unsafe.Pointer(&msg.Mask), testMask)
+		msg.Event = 1
+		msg.Nhs = 1
+		msg.Tos = 2
+		msg.Type = 3
+		msg.Table = 254
+		nh := (*internal.NextHop)(unsafe.Pointer(uintptr(p) +
+			internal.SizeofMsgFibEntry))
+		nh.Ifindex = 10
+		nh.Weight = 1
+		nh.Flags = 4
+		put(unsafe.Pointer(&nh.Gw), testIP4)
+		nh.Scope = 5
+	}},
+	{&Fib6Entry{
+		Net:     11,
+		Address: testIP6,
+		Length:  64,
+		Event:   1,
+		Type:    2,
+		Table:   255,
+		Nh:      NextHop6{Ifindex: 12, Weight: 1, Flags: 3, Gw: testGw6},
+		Siblings: []NextHop6{
+			{Ifindex: 13, Weight: 2, Flags: 4, Gw: testGw6},
+		},
+	}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgFib6Entry)(p)
+		msg.Net = 11
+		put(unsafe.Pointer(&msg.Address), testIP6)
+		msg.Length = 64
+		msg.Event = 1
+		msg.Nsiblings = 1
+		msg.Type = 2
+		msg.Table = 255
+		msg.Nh.Ifindex = 12
+		msg.Nh.Weight = 1
+		msg.Nh.Flags = 3
+		put(unsafe.Pointer(&msg.Nh.Gw), testGw6)
+		nh := (*internal.NextHop6)(unsafe.Pointer(uintptr(p) +
+			internal.SizeofMsgFib6Entry))
+		nh.Ifindex = 13
+		nh.Weight = 2
+		nh.Flags = 4
+		put(unsafe.Pointer(&nh.Gw), testGw6)
+	}},
+	{&Ifa{Xid: 14, Event: IfaAdd, Address: testIP4, Mask: testMask},
+		func(p unsafe.Pointer) {
+			msg := (*internal.MsgIfa)(p)
+			msg.Xid = 14
+			msg.Event = IfaAdd
+			put(unsafe.Pointer(&msg.Address), testIP4)
+			put(unsafe.Pointer(&msg.Mask), testMask)
+		}},
+	{&Ifa6{Xid: 15, Event: IfaDel, Address: testIP6, Length: 64},
+		func(p unsafe.Pointer) {
+			msg := (*internal.MsgIfa6)(p)
+			msg.Xid = 15
+			msg.Event = IfaDel
+			put(unsafe.Pointer(&msg.Address), testIP6)
+			msg.Length = 64
+		}},
+	{&IfInfo{
+		Xid:      16,
+		Kdata:    17,
+		Ifname:   "xeth16",
+		Net:      18,
+		Ifindex:  19,
+		Flags:    20,
+		Addr:     testHa,
+		DevKind:  xeth.DevKindPort,
+		Reason:   ReasonNew,
+		Features: 1 << 40,
+	}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgIfInfo)(p)
+		msg.Xid = 16
+		msg.Kdata = 17
+		put(unsafe.Pointer(&msg.Ifname), []byte("xeth16"))
+		msg.Net = 18
+		msg.Ifindex = 19
+		msg.Flags = 20
+		put(unsafe.Pointer(&msg.Addr), testHa)
+		msg.Kind = uint8(xeth.DevKindPort)
+		msg.Reason = ReasonNew
+		msg.Features = 1 << 40
+	}},
+	{&NeighUpdate{
+		Net:     21,
+		Ifindex: 22,
+		Family:  syscall.AF_INET,
+		DstLen:  32,
+		Dst:     testIP4,
+		Lladdr:  testHa,
+	}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgNeighUpdate)(p)
+		msg.Net = 21
+		msg.Ifindex = 22
+		msg.Family = syscall.AF_INET
+		msg.Len = 32
+		put(unsafe.Pointer(&msg.Dst), testIP4)
+		put(unsafe.Pointer(&msg.Lladdr), testHa)
+	}},
+	{&NeighUpdate{
+		Net:     23,
+		Ifindex: 24,
+		Family:  syscall.AF_INET6,
+		DstLen:  128,
+		Dst:     testIP6,
+		Lladdr:  testHa,
+	}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgNeighUpdate)(p)
+		msg.Net = 23
+		msg.Ifindex = 24
+		msg.Family = syscall.AF_INET6
+		msg.Len = 128
+		put(unsafe.Pointer(&msg.Dst), testIP6)
+		put(unsafe.Pointer(&msg.Lladdr), testHa)
+	}},
+	{&NetNs{MsgKind: xeth.MsgKindNetNsAdd, Net: 25},
+		func(p unsafe.Pointer) {
+			(*internal.MsgNetNs)(p).Net = 25
+		}},
+	{&Speed{Xid: 26, Mbps: 25000}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgSpeed)(p)
+		msg.Xid = 26
+		msg.Mbps = 25000
+	}},
+	{&EthtoolPause{Xid: 27, AutoNeg: true, Tx: true},
+		func(p unsafe.Pointer) {
+			msg := (*internal.MsgEthtoolPause)(p)
+			msg.Xid = 27
+			msg.Autoneg = 1
+			msg.Tx_pause = 1
+		}},
+	{&EthtoolFec{Xid: 28, Fec: 0x6, Active: 0x4}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgEthtoolFec)(p)
+		msg.Xid = 28
+		msg.Fec = 0x6
+		msg.Active_fec = 0x4
+	}},
+	{&EthtoolEee{
+		Xid:          29,
+		Supported:    0x1,
+		Advertised:   0x2,
+		LPAdvertised: 0x4,
+		TxLpiTimer:   30,
+		Active:       true,
+		TxLpiEnabled: true,
+	}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgEthtoolEee)(p)
+		msg.Xid = 29
+		msg.Supported = 0x1
+		msg.Advertised = 0x2
+		msg.Lp_advertised = 0x4
+		msg.Tx_lpi_timer = 30
+		msg.Eee_active = 1
+		msg.Tx_lpi_enabled = 1
+	}},
+	{&Stat{
+		MsgKind: xeth.MsgKindLinkStat,
+		Xid:     31,
+		Index:   2,
+		Count:   1 << 33,
+	}, func(p unsafe.Pointer) {
+		msg := (*internal.MsgStat)(p)
+		msg.Xid = 31
+		msg.Index = 2
+		msg.Count = 1 << 33
+	}},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tc := range testMsgs {
+		b, err := Marshal(tc.msg)
+		if err != nil {
+			t.Error(tc.msg.Kind(), err)
+			continue
+		}
+		if len(b) != tc.msg.Len() {
+			t.Error(tc.msg.Kind(), "len", len(b))
+		}
+		msg, err := Unmarshal(b)
+		if err != nil {
+			t.Error(tc.msg.Kind(), err)
+		} else if !reflect.DeepEqual(msg, tc.msg) {
+			t.Errorf("%v: got %+v", tc.msg.Kind(), msg)
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	for _, tc := range testMsgs {
+		b, err := Marshal(tc.msg)
+		if err != nil {
+			t.Error(tc.msg.Kind(), err)
+			continue
+		}
+		want := make([]byte, tc.msg.Len())
+		p := unsafe.Pointer(&want[0])
+		(*internal.MsgHeader)(p).Set(uint8(tc.msg.Kind()))
+		tc.want(p)
+		if !bytes.Equal(b, want) {
+			t.Errorf("%v:\ngot  %x\nwant %x", tc.msg.Kind(), b, want)
+		}
+	}
+}
+
+// a version 2 pause, which is newer than its version
+func pause2() []byte {
+	b, _ := Marshal(&EthtoolPause{Xid: 1})
+	b[sizeofHeader-2] = MinVersion
+	return b
+}
+
+func TestErrors(t *testing.T) {
+	b, err := Marshal(&Speed{Xid: 1, Mbps: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		b    []byte
+		err  error
+	}{
+		{"short header", b[:sizeofHeader-1], ErrShort},
+		{"short", b[:len(b)-1], ErrShort},
+		{"header", append([]byte{1}, b[1:]...), ErrHeader},
+		{"version", append(append([]byte{}, b[:sizeofHeader-2]...),
+			Version+1, b[sizeofHeader-1]), ErrVersion},
+		{"kind version", pause2(), ErrVersion},
+		{"kind", append(append([]byte{}, b[:sizeofHeader-1]...),
+			0xf0), ErrKind},
+	} {
+		_, err := Unmarshal(tc.b)
+		var e *Error
+		if !errors.Is(err, tc.err) || !errors.As(err, &e) {
+			t.Error(tc.name, err)
+		}
+	}
+	_, err = MarshalTo(make([]byte, 1), &Break{})
+	if !errors.Is(err, ErrShort) {
+		t.Error("marshal short", err)
+	}
+	_, err = Marshal(&Ifa{Address: testIP6})
+	if err == nil {
+		t.Error("marshal IPv6 ifa")
+	}
+}
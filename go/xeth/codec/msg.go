@@ -0,0 +1,695 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codec
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// Carrier flags
+const (
+	CarrierOff = internal.CarrierOff
+	CarrierOn  = internal.CarrierOn
+)
+
+// IfInfo reasons
+const (
+	ReasonNew      = internal.IfInfoReasonNew
+	ReasonDel      = internal.IfInfoReasonDel
+	ReasonUp       = internal.IfInfoReasonUp
+	ReasonDown     = internal.IfInfoReasonDown
+	ReasonDump     = internal.IfInfoReasonDump
+	ReasonReg      = internal.IfInfoReasonReg
+	ReasonUnreg    = internal.IfInfoReasonUnreg
+	ReasonFeatures = internal.IfInfoReasonFeatures
+)
+
+// Ifa events
+const (
+	IfaAdd = internal.IFA_ADD
+	IfaDel = internal.IFA_DEL
+)
+
+const (
+	sizeofNextHop  = internal.SizeofNextHop
+	sizeofNextHop6 = internal.SizeofNextHop6
+	maxNextHops    = 1<<8 - 1
+)
+
+type Break struct{}
+
+type DumpIfInfo struct{}
+
+type DumpFibInfo struct{}
+
+type Carrier struct {
+	Xid  xeth.Xid
+	Flag uint8
+}
+
+type ChangeUpperXid struct {
+	Upper   xeth.Xid
+	Lower   xeth.Xid
+	Linking bool
+}
+
+type EthtoolFlags struct {
+	Xid   xeth.Xid
+	Flags xeth.EthtoolFlagBits
+}
+
+type EthtoolSettings struct {
+	Xid           xeth.Xid
+	Speed         uint32
+	Duplex        xeth.Duplex
+	Port          xeth.DevPort
+	PhyAddress    uint8
+	Autoneg       xeth.AutoNeg
+	MdioSupport   xeth.MdioSupport
+	EthTpMdix     xeth.EthTpMdix
+	EthTpMdixCtrl xeth.EthTpMdix
+}
+
+// EthtoolLinkModes is the supported, advertising, or link partner
+// advertising message per its MsgKind. Modes beyond the first word widen
+// the message by a word each, so it decodes every whole word that follows.
+type EthtoolLinkModes struct {
+	MsgKind xeth.MsgKind
+	Xid     xeth.Xid
+	Modes   xeth.EthtoolLinkModeBits
+}
+
+type NextHop struct {
+	Ifindex int32
+	Weight  int32
+	Flags   xeth.RtnhFlags
+	Gw      net.IP
+	Scope   xeth.RtScope
+}
+
+type FibEntry struct {
+	Net      xeth.NetNs
+	Address  net.IP
+	Mask     net.IPMask
+	Event    xeth.FibEntryEvent
+	Tos      uint8
+	Type     xeth.Rtn
+	Table    xeth.RtTable
+	NextHops []NextHop
+}
+
+type NextHop6 struct {
+	Ifindex int32
+	Weight  int32
+	Flags   xeth.RtnhFlags
+	Gw      net.IP
+}
+
+type Fib6Entry struct {
+	Net      xeth.NetNs
+	Address  net.IP
+	Length   uint8
+	Event    xeth.FibEntryEvent
+	Type     xeth.Rtn
+	Table    xeth.RtTable
+	Nh       NextHop6
+	Siblings []NextHop6
+}
+
+type Ifa struct {
+	Xid     xeth.Xid
+	Event   uint32
+	Address net.IP
+	Mask    net.IPMask
+}
+
+type Ifa6 struct {
+	Xid     xeth.Xid
+	Event   uint32
+	Address net.IP
+	Length  uint8
+}
+
+type IfInfo struct {
+	Xid      xeth.Xid
+	Kdata    uint32
+	Ifname   string
+	Net      xeth.NetNs
+	Ifindex  int32
+	Flags    uint32
+	Addr     net.HardwareAddr
+	DevKind  xeth.DevKind
+	Reason   uint8
+	Features uint64
+}
+
+type NeighUpdate struct {
+	Net     xeth.NetNs
+	Ifindex int32
+	Family  uint8
+	DstLen  uint8
+	Dst     net.IP
+	Lladdr  net.HardwareAddr
+}
+
+// NetNs is the add or delete message per its MsgKind.
+type NetNs struct {
+	MsgKind xeth.MsgKind
+	Net     xeth.NetNs
+}
+
+type Speed struct {
+	Xid  xeth.Xid
+	Mbps uint32
+}
+
+type EthtoolPause struct {
+	Xid     xeth.Xid
+	AutoNeg bool
+	Rx      bool
+	Tx      bool
+}
+
+type EthtoolFec struct {
+	Xid    xeth.Xid
+	Fec    xeth.EthtoolFecBits
+	Active xeth.EthtoolFecBits
+}
+
+// EthtoolEee has legacy 32-bit link mode masks.
+type EthtoolEee struct {
+	Xid          xeth.Xid
+	Supported    uint32
+	Advertised   uint32
+	LPAdvertised uint32
+	TxLpiTimer   uint32
+	Active       bool
+	Enabled      bool
+	TxLpiEnabled bool
+}
+
+// Stat is the link or ethtool stat message per its MsgKind.
+type Stat struct {
+	MsgKind xeth.MsgKind
+	Xid     xeth.Xid
+	Index   uint32
+	Count   uint64
+}
+
+func (*Break) Kind() xeth.MsgKind       { return xeth.MsgKindBreak }
+func (*DumpIfInfo) Kind() xeth.MsgKind  { return xeth.MsgKindDumpIfInfo }
+func (*DumpFibInfo) Kind() xeth.MsgKind { return xeth.MsgKindDumpFibInfo }
+func (*Carrier) Kind() xeth.MsgKind     { return xeth.MsgKindCarrier }
+func (*ChangeUpperXid) Kind() xeth.MsgKind {
+	return xeth.MsgKindChangeUpperXid
+}
+func (*EthtoolFlags) Kind() xeth.MsgKind { return xeth.MsgKindEthtoolFlags }
+func (*EthtoolSettings) Kind() xeth.MsgKind {
+	return xeth.MsgKindEthtoolSettings
+}
+func (msg *EthtoolLinkModes) Kind() xeth.MsgKind { return msg.MsgKind }
+func (*FibEntry) Kind() xeth.MsgKind             { return xeth.MsgKindFibEntry }
+func (*Fib6Entry) Kind() xeth.MsgKind            { return xeth.MsgKindFib6Entry }
+func (*Ifa) Kind() xeth.MsgKind                  { return xeth.MsgKindIfa }
+func (*Ifa6) Kind() xeth.MsgKind                 { return xeth.MsgKindIfa6 }
+func (*IfInfo) Kind() xeth.MsgKind               { return xeth.MsgKindIfInfo }
+func (*NeighUpdate) Kind() xeth.MsgKind          { return xeth.MsgKindNeighUpdate }
+func (msg *NetNs) Kind() xeth.MsgKind            { return msg.MsgKind }
+func (*Speed) Kind() xeth.MsgKind                { return xeth.MsgKindSpeed }
+func (msg *Stat) Kind() xeth.MsgKind             { return msg.MsgKind }
+func (*EthtoolPause) Kind() xeth.MsgKind         { return xeth.MsgKindEthtoolPause }
+func (*EthtoolFec) Kind() xeth.MsgKind           { return xeth.MsgKindEthtoolFec }
+func (*EthtoolEee) Kind() xeth.MsgKind           { return xeth.MsgKindEthtoolEee }
+
+func (*Break) Len() int           { return internal.SizeofMsgBreak }
+func (*DumpIfInfo) Len() int      { return internal.SizeofMsgDumpIfInfo }
+func (*DumpFibInfo) Len() int     { return internal.SizeofMsgDumpFibInfo }
+func (*Carrier) Len() int         { return internal.SizeofMsgCarrier }
+func (*ChangeUpperXid) Len() int  { return internal.SizeofMsgChangeUpperXid }
+func (*EthtoolFlags) Len() int    { return internal.SizeofMsgEthtoolFlags }
+func (*EthtoolSettings) Len() int { return internal.SizeofMsgEthtoolSettings }
+func (msg *EthtoolLinkModes) Len() int {
+	n := internal.SizeofMsgEthtoolLinkModes
+	if len(msg.Modes) > 1 {
+		n += 8 * (len(msg.Modes) - 1)
+	}
+	return n
+}
+func (*Ifa) Len() int          { return internal.SizeofMsgIfa }
+func (*Ifa6) Len() int         { return internal.SizeofMsgIfa6 }
+func (*IfInfo) Len() int       { return internal.SizeofMsgIfInfo }
+func (*NeighUpdate) Len() int  { return internal.SizeofMsgNeighUpdate }
+func (*NetNs) Len() int        { return internal.SizeofMsgNetNs }
+func (*Speed) Len() int        { return internal.SizeofMsgSpeed }
+func (*Stat) Len() int         { return internal.SizeofMsgStat }
+func (*EthtoolPause) Len() int { return internal.SizeofMsgEthtoolPause }
+func (*EthtoolFec) Len() int   { return internal.SizeofMsgEthtoolFec }
+func (*EthtoolEee) Len() int   { return internal.SizeofMsgEthtoolEee }
+
+func (msg *FibEntry) Len() int {
+	return internal.SizeofMsgFibEntry + len(msg.NextHops)*sizeofNextHop
+}
+
+func (msg *Fib6Entry) Len() int {
+	return internal.SizeofMsgFib6Entry + len(msg.Siblings)*sizeofNextHop6
+}
+
+func (*Break) encode(e *encoder) error       { return nil }
+func (*DumpIfInfo) encode(e *encoder) error  { return nil }
+func (*DumpFibInfo) encode(e *encoder) error { return nil }
+
+func (*Break) decode(d *decoder)       {}
+func (*DumpIfInfo) decode(d *decoder)  {}
+func (*DumpFibInfo) decode(d *decoder) {}
+
+func (msg *Carrier) encode(e *encoder) error {
+	e.u32(uint32(msg.Xid))
+	e.u8(msg.Flag)
+	e.zero(3)
+	return nil
+}
+
+func (msg *Carrier) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	msg.Flag = d.u8()
+	d.skip(3)
+}
+
+func (msg *ChangeUpperXid) encode(e *encoder) error {
+	e.u32(uint32(msg.Upper))
+	e.u32(uint32(msg.Lower))
+	if msg.Linking {
+		e.u8(1)
+	} else {
+		e.u8(0)
+	}
+	e.zero(7)
+	return nil
+}
+
+func (msg *ChangeUpperXid) decode(d *decoder) {
+	msg.Upper = xeth.Xid(d.u32())
+	msg.Lower = xeth.Xid(d.u32())
+	msg.Linking = d.u8() != 0
+	d.skip(7)
+}
+
+func (msg *EthtoolFlags) encode(e *encoder) error {
+	e.u32(uint32(msg.Xid))
+	e.u32(uint32(msg.Flags))
+	return nil
+}
+
+func (msg *EthtoolFlags) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	msg.Flags = xeth.EthtoolFlagBits(d.u32())
+}
+
+func (msg *EthtoolSettings) encode(e *encoder) error {
+	e.u32(uint32(msg.Xid))
+	e.u32(msg.Speed)
+	e.u8(uint8(msg.Duplex))
+	e.u8(uint8(msg.Port))
+	e.u8(msg.PhyAddress)
+	e.u8(uint8(msg.Autoneg))
+	e.u8(uint8(msg.MdioSupport))
+	e.u8(uint8(msg.EthTpMdix))
+	e.u8(uint8(msg.EthTpMdixCtrl))
+	e.zero(1)
+	return nil
+}
+
+func (msg *EthtoolSettings) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	msg.Speed = d.u32()
+	msg.Duplex = xeth.Duplex(d.u8())
+	msg.Port = xeth.DevPort(d.u8())
+	msg.PhyAddress = d.u8()
+	msg.Autoneg = xeth.AutoNeg(d.u8())
+	msg.MdioSupport = xeth.MdioSupport(d.u8())
+	msg.EthTpMdix = xeth.EthTpMdix(d.u8())
+	msg.EthTpMdixCtrl = xeth.EthTpMdix(d.u8())
+	d.skip(1)
+}
+
+func (msg *EthtoolLinkModes) encode(e *encoder) error {
+	e.u32(uint32(msg.Xid))
+	e.zero(4)
+	if len(msg.Modes) == 0 {
+		e.u64(0)
+	}
+	for _, w := range msg.Modes {
+		e.u64(w)
+	}
+	return nil
+}
+
+func (msg *EthtoolLinkModes) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	d.skip(4)
+	msg.Modes = xeth.EthtoolLinkModeBits{d.u64()}
+	for d.err == nil && len(d.b)-d.off >= 8 {
+		msg.Modes = append(msg.Modes, d.u64())
+	}
+}
+
+func (nh *NextHop) encode(e *encoder) error {
+	e.u32(uint32(nh.Ifindex))
+	e.u32(uint32(nh.Weight))
+	e.u32(uint32(nh.Flags))
+	if err := e.ip4(nh.Gw); err != nil {
+		return err
+	}
+	e.u8(uint8(nh.Scope))
+	e.zero(7)
+	return nil
+}
+
+func (nh *NextHop) decode(d *decoder) {
+	nh.Ifindex = int32(d.u32())
+	nh.Weight = int32(d.u32())
+	nh.Flags = xeth.RtnhFlags(d.u32())
+	nh.Gw = net.IP(d.bytes(net.IPv4len))
+	nh.Scope = xeth.RtScope(d.u8())
+	d.skip(7)
+}
+
+func (msg *FibEntry) encode(e *encoder) error {
+	if len(msg.NextHops) > maxNextHops {
+		return fmt.Errorf("%d next hops exceed %d",
+			len(msg.NextHops), maxNextHops)
+	}
+	e.u64(uint64(msg.Net))
+	if err := e.ip4(msg.Address); err != nil {
+		return err
+	}
+	if len(msg.Mask) != 0 && len(msg.Mask) != net.IPv4len {
+		return fmt.Errorf("%v isn't an IPv4 mask", msg.Mask)
+	}
+	e.bytes(msg.Mask, net.IPv4len)
+	e.u8(uint8(msg.Event))
+	e.u8(uint8(len(msg.NextHops)))
+	e.u8(msg.Tos)
+	e.u8(uint8(msg.Type))
+	e.u32(uint32(msg.Table))
+	for i := range msg.NextHops {
+		if err := msg.NextHops[i].encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (msg *FibEntry) decode(d *decoder) {
+	msg.Net = xeth.NetNs(d.u64())
+	msg.Address = net.IP(d.bytes(net.IPv4len))
+	msg.Mask = net.IPMask(d.bytes(net.IPv4len))
+	msg.Event = xeth.FibEntryEvent(d.u8())
+	nhs := int(d.u8())
+	msg.Tos = d.u8()
+	msg.Type = xeth.Rtn(d.u8())
+	msg.Table = xeth.RtTable(d.u32())
+	if !d.need(nhs * sizeofNextHop) {
+		return
+	}
+	msg.NextHops = make([]NextHop, nhs)
+	for i := range msg.NextHops {
+		msg.NextHops[i].decode(d)
+	}
+}
+
+func (nh *NextHop6) encode(e *encoder) error {
+	e.u32(uint32(nh.Ifindex))
+	e.u32(uint32(nh.Weight))
+	e.u32(uint32(nh.Flags))
+	e.zero(4)
+	return e.ip6(nh.Gw)
+}
+
+func (nh *NextHop6) decode(d *decoder) {
+	nh.Ifindex = int32(d.u32())
+	nh.Weight = int32(d.u32())
+	nh.Flags = xeth.RtnhFlags(d.u32())
+	d.skip(4)
+	nh.Gw = net.IP(d.bytes(net.IPv6len))
+}
+
+func (msg *Fib6Entry) encode(e *encoder) error {
+	if len(msg.Siblings) > maxNextHops {
+		return fmt.Errorf("%d siblings exceed %d",
+			len(msg.Siblings), maxNextHops)
+	}
+	e.u64(uint64(msg.Net))
+	if err := e.ip6(msg.Address); err != nil {
+		return err
+	}
+	e.u8(msg.Length)
+	e.u8(uint8(msg.Event))
+	e.u8(uint8(len(msg.Siblings)))
+	e.u8(uint8(msg.Type))
+	e.u32(uint32(msg.Table))
+	if err := msg.Nh.encode(e); err != nil {
+		return err
+	}
+	for i := range msg.Siblings {
+		if err := msg.Siblings[i].encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (msg *Fib6Entry) decode(d *decoder) {
+	msg.Net = xeth.NetNs(d.u64())
+	msg.Address = net.IP(d.bytes(net.IPv6len))
+	msg.Length = d.u8()
+	msg.Event = xeth.FibEntryEvent(d.u8())
+	nsiblings := int(d.u8())
+	msg.Type = xeth.Rtn(d.u8())
+	msg.Table = xeth.RtTable(d.u32())
+	msg.Nh.decode(d)
+	if !d.need(nsiblings * sizeofNextHop6) {
+		return
+	}
+	msg.Siblings = make([]NextHop6, nsiblings)
+	for i := range msg.Siblings {
+		msg.Siblings[i].decode(d)
+	}
+}
+
+func (msg *Ifa) encode(e *encoder) error {
+	e.u32(uint32(msg.Xid))
+	e.u32(msg.Event)
+	if err := e.ip4(msg.Address); err != nil {
+		return err
+	}
+	if len(msg.Mask) != 0 && len(msg.Mask) != net.IPv4len {
+		return fmt.Errorf("%v isn't an IPv4 mask", msg.Mask)
+	}
+	e.bytes(msg.Mask, net.IPv4len)
+	return nil
+}
+
+func (msg *Ifa) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	msg.Event = d.u32()
+	msg.Address = net.IP(d.bytes(net.IPv4len))
+	msg.Mask = net.IPMask(d.bytes(net.IPv4len))
+}
+
+func (msg *Ifa6) encode(e *encoder) error {
+	e.u32(uint32(msg.Xid))
+	e.u32(msg.Event)
+	if err := e.ip6(msg.Address); err != nil {
+		return err
+	}
+	e.u8(msg.Length)
+	e.zero(7)
+	return nil
+}
+
+func (msg *Ifa6) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	msg.Event = d.u32()
+	msg.Address = net.IP(d.bytes(net.IPv6len))
+	msg.Length = d.u8()
+	d.skip(7)
+}
+
+func (msg *IfInfo) encode(e *encoder) error {
+	if len(msg.Ifname) >= internal.SizeofIfName {
+		return fmt.Errorf("ifname %q exceeds %d", msg.Ifname,
+			internal.SizeofIfName-1)
+	}
+	if len(msg.Addr) != 0 && len(msg.Addr) != internal.SizeofEthAddr {
+		return fmt.Errorf("%v isn't an ethernet address", msg.Addr)
+	}
+	e.u32(uint32(msg.Xid))
+	e.u32(msg.Kdata)
+	e.bytes([]byte(msg.Ifname), internal.SizeofIfName)
+	e.u64(uint64(msg.Net))
+	e.u32(uint32(msg.Ifindex))
+	e.u32(msg.Flags)
+	e.bytes(msg.Addr, internal.SizeofEthAddr)
+	e.u8(uint8(msg.DevKind))
+	e.u8(msg.Reason)
+	e.u64(msg.Features)
+	return nil
+}
+
+func (msg *IfInfo) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	msg.Kdata = d.u32()
+	name := d.bytes(internal.SizeofIfName)
+	for i, c := range name {
+		if c == 0 {
+			name = name[:i]
+			break
+		}
+	}
+	msg.Ifname = string(name)
+	msg.Net = xeth.NetNs(d.u64())
+	msg.Ifindex = int32(d.u32())
+	msg.Flags = d.u32()
+	msg.Addr = net.HardwareAddr(d.bytes(internal.SizeofEthAddr))
+	msg.DevKind = xeth.DevKind(d.u8())
+	msg.Reason = d.u8()
+	msg.Features = d.u64()
+}
+
+// An AF_INET destination occupies the first four bytes; others use all
+// sixteen.
+func (msg *NeighUpdate) encode(e *encoder) error {
+	if len(msg.Lladdr) != 0 &&
+		len(msg.Lladdr) != internal.SizeofEthAddr {
+		return fmt.Errorf("%v isn't an ethernet address", msg.Lladdr)
+	}
+	e.u64(uint64(msg.Net))
+	e.u32(uint32(msg.Ifindex))
+	e.u8(msg.Family)
+	e.u8(msg.DstLen)
+	e.zero(2)
+	if msg.Family == syscall.AF_INET {
+		if err := e.ip4(msg.Dst); err != nil {
+			return err
+		}
+		e.zero(net.IPv6len - net.IPv4len)
+	} else if err := e.ip6(msg.Dst); err != nil {
+		return err
+	}
+	e.bytes(msg.Lladdr, internal.SizeofEthAddr)
+	e.zero(2)
+	return nil
+}
+
+func (msg *NeighUpdate) decode(d *decoder) {
+	msg.Net = xeth.NetNs(d.u64())
+	msg.Ifindex = int32(d.u32())
+	msg.Family = d.u8()
+	msg.DstLen = d.u8()
+	d.skip(2)
+	dst := d.bytes(net.IPv6len)
+	if msg.Family == syscall.AF_INET && dst != nil {
+		dst = dst[:net.IPv4len]
+	}
+	msg.Dst = net.IP(dst)
+	msg.Lladdr = net.HardwareAddr(d.bytes(internal.SizeofEthAddr))
+	d.skip(2)
+}
+
+func (msg *NetNs) encode(e *encoder) error {
+	e.u64(uint64(msg.Net))
+	return nil
+}
+
+func (msg *NetNs) decode(d *decoder) {
+	msg.Net = xeth.NetNs(d.u64())
+}
+
+func (msg *Speed) encode(e *encoder) error {
+	e.u32(uint32(msg.Xid))
+	e.u32(msg.Mbps)
+	return nil
+}
+
+func (msg *Speed) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	msg.Mbps = d.u32()
+}
+
+func (msg *EthtoolPause) encode(e *encoder) error {
+	e.u32(uint32(msg.Xid))
+	e.bool(msg.AutoNeg)
+	e.bool(msg.Rx)
+	e.bool(msg.Tx)
+	e.zero(1)
+	return nil
+}
+
+func (msg *EthtoolPause) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	msg.AutoNeg = d.bool()
+	msg.Rx = d.bool()
+	msg.Tx = d.bool()
+	d.skip(1)
+}
+
+func (msg *EthtoolFec) encode(e *encoder) error {
+	e.u32(uint32(msg.Xid))
+	e.u32(uint32(msg.Fec))
+	e.u32(uint32(msg.Active))
+	e.zero(4)
+	return nil
+}
+
+func (msg *EthtoolFec) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	msg.Fec = xeth.EthtoolFecBits(d.u32())
+	msg.Active = xeth.EthtoolFecBits(d.u32())
+	d.skip(4)
+}
+
+func (msg *EthtoolEee) encode(e *encoder) error {
+	e.u32(uint32(msg.Xid))
+	e.u32(msg.Supported)
+	e.u32(msg.Advertised)
+	e.u32(msg.LPAdvertised)
+	e.u32(msg.TxLpiTimer)
+	e.bool(msg.Active)
+	e.bool(msg.Enabled)
+	e.bool(msg.TxLpiEnabled)
+	e.zero(1)
+	return nil
+}
+
+func (msg *EthtoolEee) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	msg.Supported = d.u32()
+	msg.Advertised = d.u32()
+	msg.LPAdvertised = d.u32()
+	msg.TxLpiTimer = d.u32()
+	msg.Active = d.bool()
+	msg.Enabled = d.bool()
+	msg.TxLpiEnabled = d.bool()
+	d.skip(1)
+}
+
+func (msg *Stat) encode(e *encoder) error {
+	e.u32(uint32(msg.Xid))
+	e.u32(msg.Index)
+	e.u64(msg.Count)
+	return nil
+}
+
+func (msg *Stat) decode(d *decoder) {
+	msg.Xid = xeth.Xid(d.u32())
+	msg.Index = d.u32()
+	msg.Count = d.u64()
+}
@@ -19,3 +19,7 @@ func (count *Counter) Reset() {
 func (count *Counter) Inc() {
 	atomic.AddUint64((*uint64)(count), 1)
 }
+
+func (count *Counter) Add(n uint64) {
+	atomic.AddUint64((*uint64)(count), n)
+}
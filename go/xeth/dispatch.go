@@ -0,0 +1,454 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// Dispatcher parses received messages and calls the subscribers of each
+// kind of note. After the subscribers return, the dispatcher pools the note,
+// so a subscriber that keeps a *FibEntry or *Neighbor must Hold it then
+// Pool it when done; a subscriber that keeps a Frame must copy it.
+// The notes swept by a Break are dispatched before the Break itself.
+type Dispatcher struct {
+	mutex sync.RWMutex
+	subs  map[reflect.Type][]*Subscription
+	any   []*Subscription
+}
+
+type Subscription struct {
+	d       *Dispatcher
+	t       reflect.Type
+	f       func(interface{})
+	filters []Filter
+}
+
+// Filter returns true if the note should be delivered to the subscriber.
+type Filter func(note interface{}) bool
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		subs: make(map[reflect.Type][]*Subscription),
+	}
+}
+
+// FilterXid delivers the notes of the given xids; a fib entry matches any of
+// its next hops and a join or quit matches either its lower or upper.
+// Notes without an xid, like Break, are always delivered.
+func FilterXid(xids ...Xid) Filter {
+	return func(note interface{}) bool {
+		noted, ok := xidsOf(note)
+		if !ok {
+			return true
+		}
+		for _, xid := range noted {
+			for _, match := range xids {
+				if xid == match {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// FilterNetNs delivers the notes of links, routes, neighbors and name spaces
+// within the given name spaces. Notes without a name space are always
+// delivered.
+func FilterNetNs(nses ...NetNs) Filter {
+	return func(note interface{}) bool {
+		ns, ok := netnsOf(note)
+		if !ok {
+			return true
+		}
+		for _, match := range nses {
+			if ns == match {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// On subscribes f to the notes of the same type as the given sample, e.g.
+//
+//	d.On((*xeth.FibEntry)(nil), func(v interface{}) { ... })
+func (d *Dispatcher) On(sample interface{}, f func(interface{}),
+	filters ...Filter) *Subscription {
+	sub := &Subscription{
+		d:       d,
+		t:       reflect.TypeOf(sample),
+		f:       f,
+		filters: filters,
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.subs[sub.t] = append(d.subs[sub.t], sub)
+	return sub
+}
+
+// OnAny subscribes f to every note after those of its type.
+func (d *Dispatcher) OnAny(f func(interface{}),
+	filters ...Filter) *Subscription {
+	sub := &Subscription{
+		d:       d,
+		f:       f,
+		filters: filters,
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.any = append(d.any, sub)
+	return sub
+}
+
+func (d *Dispatcher) OnBreak(f func(Break), filters ...Filter) *Subscription {
+	return d.On(Break{}, func(v interface{}) { f(v.(Break)) }, filters...)
+}
+
+func (d *Dispatcher) OnMark(f func(Mark), filters ...Filter) *Subscription {
+	return d.On(Mark(0), func(v interface{}) { f(v.(Mark)) }, filters...)
+}
+
+func (d *Dispatcher) OnDisconnected(f func(Disconnected),
+	filters ...Filter) *Subscription {
+	return d.On(Disconnected{},
+		func(v interface{}) { f(v.(Disconnected)) }, filters...)
+}
+
+func (d *Dispatcher) OnResynced(f func(Resynced),
+	filters ...Filter) *Subscription {
+	return d.On(Resynced{},
+		func(v interface{}) { f(v.(Resynced)) }, filters...)
+}
+
+func (d *Dispatcher) OnOverrun(f func(Overrun),
+	filters ...Filter) *Subscription {
+	return d.On(Overrun{}, func(v interface{}) { f(v.(Overrun)) }, filters...)
+}
+
+func (d *Dispatcher) OnCarrierDampened(f func(CarrierDampened),
+	filters ...Filter) *Subscription {
+	return d.On(CarrierDampened{},
+		func(v interface{}) { f(v.(CarrierDampened)) }, filters...)
+}
+
+func (d *Dispatcher) OnParseError(f func(ParseError),
+	filters ...Filter) *Subscription {
+	return d.On(ParseError{},
+		func(v interface{}) { f(v.(ParseError)) }, filters...)
+}
+
+func (d *Dispatcher) OnUnknownMsg(f func(UnknownMsg),
+	filters ...Filter) *Subscription {
+	return d.On(UnknownMsg{},
+		func(v interface{}) { f(v.(UnknownMsg)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevNew(f func(DevNew), filters ...Filter) *Subscription {
+	return d.On(DevNew(0), func(v interface{}) { f(v.(DevNew)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevDel(f func(DevDel), filters ...Filter) *Subscription {
+	return d.On(DevDel(0), func(v interface{}) { f(v.(DevDel)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevDump(f func(DevDump),
+	filters ...Filter) *Subscription {
+	return d.On(DevDump(0),
+		func(v interface{}) { f(v.(DevDump)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevUp(f func(DevUp), filters ...Filter) *Subscription {
+	return d.On(DevUp(0), func(v interface{}) { f(v.(DevUp)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevDown(f func(DevDown),
+	filters ...Filter) *Subscription {
+	return d.On(DevDown(0),
+		func(v interface{}) { f(v.(DevDown)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevReg(f func(DevReg), filters ...Filter) *Subscription {
+	return d.On(DevReg(0), func(v interface{}) { f(v.(DevReg)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevUnreg(f func(DevUnreg),
+	filters ...Filter) *Subscription {
+	return d.On(DevUnreg(0),
+		func(v interface{}) { f(v.(DevUnreg)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevFeatures(f func(DevFeatures),
+	filters ...Filter) *Subscription {
+	return d.On(DevFeatures(0),
+		func(v interface{}) { f(v.(DevFeatures)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevAddIPNet(f func(*DevAddIPNet),
+	filters ...Filter) *Subscription {
+	return d.On((*DevAddIPNet)(nil),
+		func(v interface{}) { f(v.(*DevAddIPNet)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevDelIPNet(f func(*DevDelIPNet),
+	filters ...Filter) *Subscription {
+	return d.On((*DevDelIPNet)(nil),
+		func(v interface{}) { f(v.(*DevDelIPNet)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevJoin(f func(*DevJoin),
+	filters ...Filter) *Subscription {
+	return d.On((*DevJoin)(nil),
+		func(v interface{}) { f(v.(*DevJoin)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevQuit(f func(*DevQuit),
+	filters ...Filter) *Subscription {
+	return d.On((*DevQuit)(nil),
+		func(v interface{}) { f(v.(*DevQuit)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevEthtoolFlags(f func(*DevEthtoolFlags),
+	filters ...Filter) *Subscription {
+	return d.On((*DevEthtoolFlags)(nil),
+		func(v interface{}) { f(v.(*DevEthtoolFlags)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevEthtoolPause(f func(*DevEthtoolPause),
+	filters ...Filter) *Subscription {
+	return d.On((*DevEthtoolPause)(nil),
+		func(v interface{}) { f(v.(*DevEthtoolPause)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevEthtoolFec(f func(*DevEthtoolFec),
+	filters ...Filter) *Subscription {
+	return d.On((*DevEthtoolFec)(nil),
+		func(v interface{}) { f(v.(*DevEthtoolFec)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevEthtoolEee(f func(*DevEthtoolEee),
+	filters ...Filter) *Subscription {
+	return d.On((*DevEthtoolEee)(nil),
+		func(v interface{}) { f(v.(*DevEthtoolEee)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevEthtoolSettings(f func(DevEthtoolSettings),
+	filters ...Filter) *Subscription {
+	return d.On(DevEthtoolSettings{},
+		func(v interface{}) { f(v.(DevEthtoolSettings)) }, filters...)
+}
+
+func (d *Dispatcher) OnDevLinkModesSupported(f func(DevLinkModesSupported),
+	filters ...Filter) *Subscription {
+	return d.On(DevLinkModesSupported(0),
+		func(v interface{}) { f(v.(DevLinkModesSupported)) },
+		filters...)
+}
+
+func (d *Dispatcher) OnDevLinkModesAdvertising(
+	f func(DevLinkModesAdvertising), filters ...Filter) *Subscription {
+	return d.On(DevLinkModesAdvertising(0),
+		func(v interface{}) { f(v.(DevLinkModesAdvertising)) },
+		filters...)
+}
+
+func (d *Dispatcher) OnDevLinkModesLPAdvertising(
+	f func(DevLinkModesLPAdvertising), filters ...Filter) *Subscription {
+	return d.On(DevLinkModesLPAdvertising(0),
+		func(v interface{}) { f(v.(DevLinkModesLPAdvertising)) },
+		filters...)
+}
+
+func (d *Dispatcher) OnFibEntry(f func(*FibEntry),
+	filters ...Filter) *Subscription {
+	return d.On((*FibEntry)(nil),
+		func(v interface{}) { f(v.(*FibEntry)) }, filters...)
+}
+
+func (d *Dispatcher) OnNeighbor(f func(*Neighbor),
+	filters ...Filter) *Subscription {
+	return d.On((*Neighbor)(nil),
+		func(v interface{}) { f(v.(*Neighbor)) }, filters...)
+}
+
+func (d *Dispatcher) OnFrame(f func(Frame), filters ...Filter) *Subscription {
+	return d.On(Frame{}, func(v interface{}) { f(v.(Frame)) }, filters...)
+}
+
+func (d *Dispatcher) OnNetNsAdd(f func(NetNsAdd),
+	filters ...Filter) *Subscription {
+	return d.On(NetNsAdd{},
+		func(v interface{}) { f(v.(NetNsAdd)) }, filters...)
+}
+
+func (d *Dispatcher) OnNetNsDel(f func(NetNsDel),
+	filters ...Filter) *Subscription {
+	return d.On(NetNsDel{},
+		func(v interface{}) { f(v.(NetNsDel)) }, filters...)
+}
+
+// Dispatch parses then pools the message after calling its subscribers.
+func (d *Dispatcher) Dispatch(buf Buffer) {
+	note := Parse(buf)
+	if note == nil {
+		return
+	}
+	if b, ok := note.(Break); ok {
+		for _, swept := range b.Swept {
+			d.dispatch(swept)
+		}
+	}
+	d.dispatch(note)
+	Pool(note)
+}
+
+// Run dispatches the task's received messages until it closes the channel,
+// or the context is done; returns the task's error.
+func (d *Dispatcher) Run(ctx context.Context, task *Task) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case buf, ok := <-task.RxCh:
+			if !ok {
+				return task.Err()
+			}
+			d.Dispatch(buf)
+		}
+	}
+}
+
+// Cancel the subscription.
+func (sub *Subscription) Cancel() {
+	d := sub.d
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if sub.t == nil {
+		d.any = sub.delist(d.any)
+	} else if subs := sub.delist(d.subs[sub.t]); len(subs) > 0 {
+		d.subs[sub.t] = subs
+	} else {
+		delete(d.subs, sub.t)
+	}
+}
+
+func (sub *Subscription) delist(subs []*Subscription) []*Subscription {
+	for i, entry := range subs {
+		if entry == sub {
+			// copy so that a concurrent dispatch keeps its list
+			clone := make([]*Subscription, 0, len(subs)-1)
+			clone = append(clone, subs[:i]...)
+			return append(clone, subs[i+1:]...)
+		}
+	}
+	return subs
+}
+
+func (d *Dispatcher) dispatch(note interface{}) {
+	d.mutex.RLock()
+	subs := d.subs[reflect.TypeOf(note)]
+	any := d.any
+	d.mutex.RUnlock()
+	for _, list := range [][]*Subscription{subs, any} {
+		for _, sub := range list {
+			if sub.match(note) {
+				sub.f(note)
+			}
+		}
+	}
+}
+
+func (sub *Subscription) match(note interface{}) bool {
+	for _, filter := range sub.filters {
+		if !filter(note) {
+			return false
+		}
+	}
+	return true
+}
+
+func xidsOf(note interface{}) ([]Xid, bool) {
+	switch t := note.(type) {
+	case DevNew:
+		return []Xid{Xid(t)}, true
+	case DevDel:
+		return []Xid{Xid(t)}, true
+	case CarrierDampened:
+		return []Xid{t.Xid}, true
+	case DevDump:
+		return []Xid{Xid(t)}, true
+	case DevUp:
+		return []Xid{Xid(t)}, true
+	case DevDown:
+		return []Xid{Xid(t)}, true
+	case DevReg:
+		return []Xid{Xid(t)}, true
+	case DevUnreg:
+		return []Xid{Xid(t)}, true
+	case DevFeatures:
+		return []Xid{Xid(t)}, true
+	case DevLinkModesSupported:
+		return []Xid{Xid(t)}, true
+	case DevLinkModesAdvertising:
+		return []Xid{Xid(t)}, true
+	case DevLinkModesLPAdvertising:
+		return []Xid{Xid(t)}, true
+	case *DevAddIPNet:
+		return []Xid{t.Xid}, true
+	case *DevDelIPNet:
+		return []Xid{t.Xid}, true
+	case *DevEthtoolFlags:
+		return []Xid{t.Xid}, true
+	case DevEthtoolSettings:
+		return []Xid{t.Xid}, true
+	case *DevEthtoolPause:
+		return []Xid{t.Xid}, true
+	case *DevEthtoolFec:
+		return []Xid{t.Xid}, true
+	case *DevEthtoolEee:
+		return []Xid{t.Xid}, true
+	case *DevJoin:
+		return []Xid{t.Lower, t.Upper}, true
+	case *DevQuit:
+		return []Xid{t.Lower, t.Upper}, true
+	case *Neighbor:
+		return []Xid{t.Xid}, true
+	case *FibEntry:
+		xids := make([]Xid, 0, len(t.NHs))
+		for _, nh := range t.NHs {
+			xids = append(xids, nh.Xid)
+		}
+		return xids, true
+	case Frame:
+		return []Xid{t.Xid()}, true
+	}
+	return nil, false
+}
+
+func netnsOf(note interface{}) (NetNs, bool) {
+	switch t := note.(type) {
+	case *FibEntry:
+		return t.NetNs, true
+	case *Neighbor:
+		return t.NetNs, true
+	case NetNsAdd:
+		return t.NetNs, true
+	case NetNsDel:
+		return t.NetNs, true
+	case DevDel:
+		if v, ok := deletedNetNs.Load(Xid(t)); ok {
+			return v.(NetNs), true
+		}
+	}
+	if xids, ok := xidsOf(note); ok && len(xids) > 0 {
+		if l := LinkOf(xids[0]); l != nil {
+			return l.IfInfoNetNs(), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,112 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+func testIfInfo(xid Xid, netns NetNs, reason uint8) buffer {
+	buf := newBuffer(internal.SizeofMsgIfInfo)
+	b := buf.bytes()
+	for i := range b {
+		b[i] = 0
+	}
+	msg := (*internal.MsgIfInfo)(buf.pointer())
+	msg.Header.Set(internal.MsgKindIfInfo)
+	msg.Xid = uint32(xid)
+	msg.Net = uint64(netns)
+	msg.Kind = uint8(DevKindPort)
+	msg.Reason = reason
+	for i, c := range fmt.Sprint("xeth", xid) {
+		msg.Ifname[i] = uint8(c)
+	}
+	return buf
+}
+
+func testNeighUpdate(netns NetNs, ip net.IP, ha net.HardwareAddr) buffer {
+	buf := newBuffer(internal.SizeofMsgNeighUpdate)
+	b := buf.bytes()
+	for i := range b {
+		b[i] = 0
+	}
+	msg := (*internal.MsgNeighUpdate)(buf.pointer())
+	msg.Header.Set(internal.MsgKindNeighUpdate)
+	msg.Net = uint64(netns)
+	msg.Family = syscall.AF_INET
+	copy(msg.Dst[:], ip.To4())
+	copy(msg.Lladdr[:], ha)
+	return buf
+}
+
+func TestDispatchFilters(t *testing.T) {
+	const ns1, ns2 = NetNs(1001), NetNs(1002)
+	d := NewDispatcher()
+	var news, dels, anys int
+	d.OnDevNew(func(xid DevNew) {
+		if xid != 201 {
+			t.Error("new", xid)
+		}
+		news++
+	}, FilterXid(201))
+	d.OnDevDel(func(xid DevDel) {
+		if xid != 201 {
+			t.Error("del", xid)
+		}
+		dels++
+	}, FilterNetNs(ns1))
+	sub := d.OnAny(func(interface{}) { anys++ })
+	d.Dispatch(testIfInfo(201, ns1, internal.IfInfoReasonNew))
+	d.Dispatch(testIfInfo(202, ns2, internal.IfInfoReasonNew))
+	sub.Cancel()
+	// the name space of a deleted link is that before its delete
+	d.Dispatch(testIfInfo(201, ns1, internal.IfInfoReasonDel))
+	d.Dispatch(testIfInfo(202, ns2, internal.IfInfoReasonDel))
+	if news != 1 || dels != 1 || anys != 2 {
+		t.Error("news", news, "dels", dels, "anys", anys)
+	}
+}
+
+func TestDispatchPool(t *testing.T) {
+	const ns = NetNs(1003)
+	ip := net.IPv4(10, 6, 0, 1)
+	ha := net.HardwareAddr{2, 0, 0, 0, 0, 2}
+	d := NewDispatcher()
+	var held, pooled *Neighbor
+	sub := d.OnNeighbor(func(neigh *Neighbor) {
+		neigh.Hold()
+		held = neigh
+	})
+	d.Dispatch(testNeighUpdate(ns, ip, ha))
+	sub.Cancel()
+	// the cache and the subscriber hold the neighbor
+	if n := held.Count(); n != 2 {
+		t.Fatal("held", n)
+	}
+	d.OnNeighbor(func(neigh *Neighbor) { pooled = neigh })
+	// replaced in the cache, the held neighbor remains
+	d.Dispatch(testNeighUpdate(ns, ip, net.HardwareAddr{2, 0, 0, 0, 0, 3}))
+	if n := held.Count(); n != 1 ||
+		held.HardwareAddr.String() != ha.String() {
+		t.Error("replaced", n, held.HardwareAddr)
+	}
+	held.Pool()
+	if n := held.Count(); n != 0 {
+		t.Error("released", n)
+	}
+	// the dispatcher released its own reference
+	if n := pooled.Count(); n != 1 {
+		t.Error("pooled", n)
+	}
+	d.Dispatch(testNeighUpdate(ns, ip, nil))
+	if n := pooled.Count(); n != 0 {
+		t.Error("deleted", n)
+	}
+}
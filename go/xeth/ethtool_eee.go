@@ -0,0 +1,76 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"context"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// EthtoolEee is the energy efficient ethernet of ethtool --set-eee. The
+// link modes are limited to the first 32.
+type EthtoolEee struct {
+	Supported    EthtoolLinkModeBits
+	Advertised   EthtoolLinkModeBits
+	LPAdvertised EthtoolLinkModeBits
+	TxLpiTimer   uint32 // usec
+	Active       bool
+	Enabled      bool
+	TxLpiEnabled bool
+}
+
+type DevEthtoolEee struct {
+	Xid
+	EthtoolEee
+}
+
+func (xid Xid) RxEthtoolEee(msg *internal.MsgEthtoolEee) *DevEthtoolEee {
+	dev := &DevEthtoolEee{xid, EthtoolEee{
+		Supported:    EthtoolLinkModeBits{uint64(msg.Supported)},
+		Advertised:   EthtoolLinkModeBits{uint64(msg.Advertised)},
+		LPAdvertised: EthtoolLinkModeBits{uint64(msg.Lp_advertised)},
+		TxLpiTimer:   msg.Tx_lpi_timer,
+		Active:       msg.Eee_active != 0,
+		Enabled:      msg.Eee_enabled != 0,
+		TxLpiEnabled: msg.Tx_lpi_enabled != 0,
+	}}
+	if l := LinkOf(xid); l != nil {
+		l.EthtoolEee(dev.EthtoolEee)
+	}
+	return dev
+}
+
+// Send the EEE status to driver through hi-priority channel.
+func (task *Task) SetEee(ctx context.Context, xid Xid, eee EthtoolEee) error {
+	buf := newBuffer(internal.SizeofMsgEthtoolEee)
+	msg := (*internal.MsgEthtoolEee)(buf.pointer())
+	msg.Header.Set(internal.MsgKindEthtoolEee)
+	msg.Xid = uint32(xid)
+	msg.Supported = eee.Supported.word32()
+	msg.Advertised = eee.Advertised.word32()
+	msg.Lp_advertised = eee.LPAdvertised.word32()
+	msg.Tx_lpi_timer = eee.TxLpiTimer
+	msg.Eee_active = boolByte(eee.Active)
+	msg.Eee_enabled = boolByte(eee.Enabled)
+	msg.Tx_lpi_enabled = boolByte(eee.TxLpiEnabled)
+	return task.hi(ctx, buf)
+}
+
+// the legacy 32-bit mask of the first modes
+func (modes EthtoolLinkModeBits) word32() uint32 {
+	if len(modes) == 0 {
+		return 0
+	}
+	return uint32(modes[0])
+}
+
+// with its own link mode bitmaps
+func (eee EthtoolEee) copy() EthtoolEee {
+	eee.Supported = eee.Supported.Copy()
+	eee.Advertised = eee.Advertised.Copy()
+	eee.LPAdvertised = eee.LPAdvertised.Copy()
+	return eee
+}
@@ -0,0 +1,52 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"context"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// EthtoolFecBits are the ETHTOOL_FEC_* modes.
+type EthtoolFecBits uint32
+
+// EthtoolFec has the modes configured by ethtool --set-fec and the active
+// mode.
+type EthtoolFec struct {
+	Configured EthtoolFecBits
+	Active     EthtoolFecBits
+}
+
+type DevEthtoolFec struct {
+	Xid
+	EthtoolFec
+}
+
+func (xid Xid) RxEthtoolFec(msg *internal.MsgEthtoolFec) *DevEthtoolFec {
+	dev := &DevEthtoolFec{xid, EthtoolFec{
+		Configured: EthtoolFecBits(msg.Fec),
+		Active:     EthtoolFecBits(msg.Active_fec),
+	}}
+	if l := LinkOf(xid); l != nil {
+		l.EthtoolFec(dev.EthtoolFec)
+	}
+	return dev
+}
+
+// Send the configured and active FEC to driver through hi-priority channel.
+func (task *Task) SetFec(ctx context.Context, xid Xid, fec EthtoolFec) error {
+	buf := newBuffer(internal.SizeofMsgEthtoolFec)
+	msg := (*internal.MsgEthtoolFec)(buf.pointer())
+	msg.Header.Set(internal.MsgKindEthtoolFec)
+	msg.Xid = uint32(xid)
+	msg.Fec = uint32(fec.Configured)
+	msg.Active_fec = uint32(fec.Active)
+	return task.hi(ctx, buf)
+}
+
+func (bits EthtoolFecBits) Has(fec EthtoolFecBits) bool {
+	return bits&fec == fec
+}
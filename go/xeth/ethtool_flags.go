@@ -12,9 +12,9 @@ type DevEthtoolFlags struct {
 }
 
 func (xid Xid) RxEthtoolFlags(flags uint32) *DevEthtoolFlags {
-	l := LinkOf(xid)
 	bits := EthtoolFlagBits(flags)
-	if flags == 0 {
+	if l := expectLinkOf(xid, "RxEthtoolFlags"); l == nil {
+	} else if flags == 0 {
 		l.Delete(LinkAttrEthtoolFlags)
 	} else {
 		l.EthtoolFlags(bits)
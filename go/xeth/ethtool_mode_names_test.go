@@ -0,0 +1,114 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEthtoolLinkModeNames(t *testing.T) {
+	for bit, name := range ethtoolLinkModeNames {
+		mode := EthtoolLinkMode(bit)
+		if s := mode.String(); s != name {
+			t.Error(bit, "string", s)
+		}
+		// as printed by Format, ethtool, and in other case
+		for _, s := range []string{
+			name,
+			strings.Replace(name, "-full", "/Full", 1),
+			strings.ToUpper(name),
+		} {
+			if got, err := ParseEthtoolLinkMode(s); err != nil ||
+				got != mode {
+				t.Error(bit, "parse", s, got, err)
+			}
+		}
+	}
+	beyond := EthtoolLinkMode(len(ethtoolLinkModeNames))
+	if got, err := ParseEthtoolLinkMode(beyond.String()); err != nil ||
+		got != beyond {
+		t.Error("parse", beyond, got, err)
+	}
+	if _, err := ParseEthtoolLinkMode("10baseX-full"); err == nil {
+		t.Error("parsed unknown")
+	}
+}
+
+func TestEthtoolLinkModeInfo(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		info EthtoolLinkModeInfo
+	}{
+		{"10baseT-half", EthtoolLinkModeInfo{
+			Speed: 10, Duplex: DUPLEX_HALF, Lanes: 1, Media: "T"}},
+		{"Autoneg", EthtoolLinkModeInfo{Duplex: DUPLEX_UNKNOWN}},
+		{"fec-llrs", EthtoolLinkModeInfo{Duplex: DUPLEX_UNKNOWN}},
+		{"100baseT1-full", EthtoolLinkModeInfo{
+			Speed: 100, Duplex: DUPLEX_FULL, Lanes: 1, Media: "T1"}},
+		{"200000baseLR4-ER4-FR4-full", EthtoolLinkModeInfo{
+			Speed: 200000, Duplex: DUPLEX_FULL, Lanes: 4,
+			Media: "LR-ER-FR"}},
+		{"400000baseCR8-full", EthtoolLinkModeInfo{
+			Speed: 400000, Duplex: DUPLEX_FULL, Lanes: 8,
+			Media: "CR"}},
+		{"100baseFX-full", EthtoolLinkModeInfo{
+			Speed: 100, Duplex: DUPLEX_FULL, Lanes: 1, Media: "FX"}},
+	} {
+		mode, err := ParseEthtoolLinkMode(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc.info.Name = tc.name
+		if info := mode.Info(); info != tc.info {
+			t.Errorf("%s: %+v", tc.name, info)
+		}
+	}
+}
+
+func TestResolveLinkMode(t *testing.T) {
+	modes := func(names string) EthtoolLinkModeBits {
+		bits, err := ParseEthtoolLinkModes(names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bits
+	}
+	for _, tc := range []struct {
+		supported, advertising, partner string
+		mode                            string
+		speed                           uint32
+	}{
+		{
+			"1000baseT-full 400000baseCR8-full 400000baseCR4-full",
+			"1000baseT-full 400000baseCR8-full 400000baseCR4-full",
+			"1000baseT-full 400000baseCR4-full",
+			"400000baseCR4-full", 400000,
+		},
+		{
+			"10baseT-half 10baseT-full",
+			"10baseT-half 10baseT-full",
+			"10baseT-half 10baseT-full",
+			"10baseT-full", 10,
+		},
+		{
+			"100000baseCR-full 100000baseKR-full",
+			"100000baseCR-full 100000baseKR-full",
+			"100000baseCR-full 100000baseKR-full",
+			"100000baseCR-full", 100000,
+		},
+		{"Autoneg 10baseT-full", "Autoneg", "Autoneg", "", 0},
+	} {
+		mode, speed, ok := ResolveLinkMode(modes(tc.supported),
+			modes(tc.advertising), modes(tc.partner))
+		if tc.mode == "" {
+			if ok {
+				t.Error("resolved", mode)
+			}
+		} else if !ok || mode.String() != tc.mode || speed != tc.speed {
+			t.Error(tc.mode, "resolved", mode, speed, ok)
+		}
+	}
+}
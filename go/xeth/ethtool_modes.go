@@ -4,37 +4,402 @@
 
 package xeth
 
-type EthtoolLinkModeBits uint64
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// EthtoolLinkModeBits is a bitmap of ethtool link modes; bit n is in word
+// n/64. Missing words are zero so the empty bitmap has no modes.
+type EthtoolLinkModeBits []uint64
+
+// EthtoolLinkMode is the bit number of an ethtool link mode.
+type EthtoolLinkMode uint
+
+// EthtoolLinkModeInfo describes a link mode. Speed is zero and Duplex is
+// DUPLEX_UNKNOWN for those that aren't a speed, like Autoneg or fec-rs.
+type EthtoolLinkModeInfo struct {
+	Name   string
+	Speed  uint32 // Mbps
+	Duplex Duplex
+	Lanes  uint8
+	// Media of the PMD with lane counts removed, e.g. "CR" of
+	// 100000baseCR4-full or "LR-ER-FR" of 100000baseLR4-ER4-FR4-full
+	Media string
+}
 
 type DevLinkModesSupported Xid
 type DevLinkModesAdvertising Xid
 type DevLinkModesLPAdvertising Xid
 
-func (xid Xid) RxSupported(modes uint64) DevLinkModesSupported {
+// The link mode messages have one or more words of modes.
+func (xid Xid) RxSupported(modes ...uint64) DevLinkModesSupported {
 	if l := LinkOf(xid); l != nil {
 		l.LinkModesSupported(EthtoolLinkModeBits(modes))
 	}
 	return DevLinkModesSupported(xid)
 }
 
-func (xid Xid) RxAdvertising(modes uint64) DevLinkModesAdvertising {
+func (xid Xid) RxAdvertising(modes ...uint64) DevLinkModesAdvertising {
 	if l := LinkOf(xid); l != nil {
 		l.LinkModesAdvertising(EthtoolLinkModeBits(modes))
 	}
 	return DevLinkModesAdvertising(xid)
 }
 
-func (xid Xid) RxLPAdvertising(modes uint64) DevLinkModesLPAdvertising {
+func (xid Xid) RxLPAdvertising(modes ...uint64) DevLinkModesLPAdvertising {
 	if l := LinkOf(xid); l != nil {
 		l.LinkModesLPAdvertising(EthtoolLinkModeBits(modes))
 	}
 	return DevLinkModesLPAdvertising(xid)
 }
 
-func (bits EthtoolLinkModeBits) Test(bit uint) bool {
-	if bit < 64 {
-		mask := EthtoolLinkModeBits(1 << bit)
-		return (bits & mask) == mask
+func (modes EthtoolLinkModeBits) Test(bit uint) bool {
+	if i := int(bit / 64); i < len(modes) {
+		mask := uint64(1) << (bit % 64)
+		return (modes[i] & mask) == mask
 	}
 	return false
 }
+
+// Set returns the bitmap, grown as necessary, with the given bits set.
+func (modes EthtoolLinkModeBits) Set(set ...uint) EthtoolLinkModeBits {
+	for _, bit := range set {
+		for int(bit/64) >= len(modes) {
+			modes = append(modes, 0)
+		}
+		modes[bit/64] |= uint64(1) << (bit % 64)
+	}
+	return modes
+}
+
+// Clear returns the bitmap with the given bits cleared.
+func (modes EthtoolLinkModeBits) Clear(clear ...uint) EthtoolLinkModeBits {
+	for _, bit := range clear {
+		if i := int(bit / 64); i < len(modes) {
+			modes[i] &^= uint64(1) << (bit % 64)
+		}
+	}
+	return modes
+}
+
+func (modes EthtoolLinkModeBits) Copy() EthtoolLinkModeBits {
+	return append(EthtoolLinkModeBits(nil), modes...)
+}
+
+// And returns a new bitmap of the modes in both.
+func (modes EthtoolLinkModeBits) And(other EthtoolLinkModeBits) EthtoolLinkModeBits {
+	n := len(modes)
+	if len(other) < n {
+		n = len(other)
+	}
+	and := make(EthtoolLinkModeBits, n)
+	for i := range and {
+		and[i] = modes[i] & other[i]
+	}
+	return and
+}
+
+// Or returns a new bitmap of the modes in either.
+func (modes EthtoolLinkModeBits) Or(other EthtoolLinkModeBits) EthtoolLinkModeBits {
+	if len(other) > len(modes) {
+		modes, other = other, modes
+	}
+	or := modes.Copy()
+	for i, w := range other {
+		or[i] |= w
+	}
+	return or
+}
+
+// AndNot returns a new bitmap of the modes not in other.
+func (modes EthtoolLinkModeBits) AndNot(other EthtoolLinkModeBits) EthtoolLinkModeBits {
+	andNot := modes.Copy()
+	for i := 0; i < len(andNot) && i < len(other); i++ {
+		andNot[i] &^= other[i]
+	}
+	return andNot
+}
+
+// Equal compares modes ignoring missing or trailing zero words.
+func (modes EthtoolLinkModeBits) Equal(other EthtoolLinkModeBits) bool {
+	if len(other) > len(modes) {
+		modes, other = other, modes
+	}
+	for i, w := range modes {
+		if i < len(other) {
+			if w != other[i] {
+				return false
+			}
+		} else if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (modes EthtoolLinkModeBits) IsZero() bool {
+	for _, w := range modes {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of modes.
+func (modes EthtoolLinkModeBits) Count() (n int) {
+	for _, w := range modes {
+		n += bits.OnesCount64(w)
+	}
+	return
+}
+
+// Bits returns the modes in ascending order.
+func (modes EthtoolLinkModeBits) Bits() []uint {
+	set := make([]uint, 0, modes.Count())
+	for i, w := range modes {
+		for w != 0 {
+			bit := uint(bits.TrailingZeros64(w))
+			set = append(set, uint(i)*64+bit)
+			w &^= uint64(1) << bit
+		}
+	}
+	return set
+}
+
+// Highest returns the highest numbered mode, which is usually, but not
+// always, the latest and fastest; false if none.
+func (modes EthtoolLinkModeBits) Highest() (uint, bool) {
+	for i := len(modes) - 1; i >= 0; i-- {
+		if w := modes[i]; w != 0 {
+			return uint(i)*64 + uint(63-bits.LeadingZeros64(w)), true
+		}
+	}
+	return 0, false
+}
+
+// HighestCommon returns the highest numbered mode of all given bitmaps;
+// false if they have none in common.
+func HighestCommon(modes ...EthtoolLinkModeBits) (uint, bool) {
+	if len(modes) == 0 {
+		return 0, false
+	}
+	common := modes[0]
+	for _, other := range modes[1:] {
+		common = common.And(other)
+	}
+	return common.Highest()
+}
+
+// ParseEthtoolLinkMode returns the mode of a name printed by Format, like
+// "100000baseCR4-full", or by ethtool, like "100000baseCR4/Full".
+// Case, '/', '_', and '-' are insignificant.
+func ParseEthtoolLinkMode(name string) (EthtoolLinkMode, error) {
+	key := linkModeKey(name)
+	for bit, s := range ethtoolLinkModeNames {
+		if linkModeKey(s) == key {
+			return EthtoolLinkMode(bit), nil
+		}
+	}
+	if strings.HasPrefix(key, "bit") {
+		if bit, err := strconv.ParseUint(key[3:], 10, 16); err == nil {
+			return EthtoolLinkMode(bit), nil
+		}
+	}
+	return 0, fmt.Errorf("%q: unknown link mode", name)
+}
+
+// ParseEthtoolLinkModes returns the bitmap of names, each of which may be a
+// list separated by commas or spaces such as printed by Format.
+func ParseEthtoolLinkModes(names ...string) (EthtoolLinkModeBits, error) {
+	var modes EthtoolLinkModeBits
+	for _, list := range names {
+		for _, name := range strings.FieldsFunc(list, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		}) {
+			if name == "none" {
+				continue
+			}
+			mode, err := ParseEthtoolLinkMode(name)
+			if err != nil {
+				return nil, err
+			}
+			modes = modes.Set(uint(mode))
+		}
+	}
+	return modes, nil
+}
+
+func linkModeKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '_', '-':
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// Info derives the mode's speed, duplex, lanes and media from its name.
+func (mode EthtoolLinkMode) Info() EthtoolLinkModeInfo {
+	info := EthtoolLinkModeInfo{
+		Name:   mode.String(),
+		Duplex: DUPLEX_UNKNOWN,
+	}
+	name := info.Name
+	duplex := Duplex(DUPLEX_UNKNOWN)
+	if strings.HasSuffix(name, "-full") {
+		duplex = DUPLEX_FULL
+	} else if strings.HasSuffix(name, "-half") {
+		duplex = DUPLEX_HALF
+	} else {
+		return info
+	}
+	name = name[:len(name)-len("-full")]
+	i := strings.Index(name, "base")
+	if i < 0 {
+		return info
+	}
+	speed, err := strconv.ParseUint(name[:i], 10, 32)
+	if err != nil {
+		return info
+	}
+	info.Speed = uint32(speed)
+	info.Duplex = duplex
+	info.Lanes = 1
+	pmds := strings.Split(name[i+len("base"):], "-")
+	for j, pmd := range pmds {
+		if pmd == "T1" {
+			// single pair automotive ethernet
+			continue
+		}
+		media := strings.TrimRight(pmd, "0123456789")
+		if j == 0 && len(media) < len(pmd) {
+			lanes, _ := strconv.ParseUint(pmd[len(media):], 10, 8)
+			info.Lanes = uint8(lanes)
+		}
+		pmds[j] = media
+	}
+	info.Media = strings.Join(pmds, "-")
+	return info
+}
+
+// ResolveLinkMode returns the fastest mode and its speed in Mbps common to
+// the supported, advertising and link partner modes, as negotiated by
+// autoneg. Full duplex wins a tie, then the higher numbered mode.
+func ResolveLinkMode(supported, advertising, partner EthtoolLinkModeBits) (mode EthtoolLinkMode, speed uint32, ok bool) {
+	var best EthtoolLinkModeInfo
+	for _, bit := range supported.And(advertising).And(partner).Bits() {
+		info := EthtoolLinkMode(bit).Info()
+		switch {
+		case info.Speed == 0:
+			continue
+		case info.Speed < best.Speed:
+			continue
+		case info.Speed == best.Speed &&
+			info.Duplex == DUPLEX_HALF &&
+			best.Duplex == DUPLEX_FULL:
+			continue
+		}
+		mode, best, ok = EthtoolLinkMode(bit), info, true
+	}
+	return mode, best.Speed, ok
+}
+
+var ethtoolLinkModeNames = []string{
+	"10baseT-half",
+	"10baseT-full",
+	"100baseT-half",
+	"100baseT-full",
+	"1000baseT-half",
+	"1000baseT-full",
+	"Autoneg",
+	"TP",
+	"AUI",
+	"MII",
+	"FIBRE",
+	"BNC",
+	"10000baseT-full",
+	"Pause",
+	"Asym-Pause",
+	"2500baseX-full",
+	"Backplane",
+	"1000baseKX-full",
+	"10000baseKX4-full",
+	"10000baseKR-full",
+	"10000baseR-FEC",
+	"20000baseMLD2-full",
+	"20000baseKR2-full",
+	"40000baseKR4-full",
+	"40000baseCR4-full",
+	"40000baseSR4-full",
+	"40000baseLR4-full",
+	"56000baseKR4-full",
+	"56000baseCR4-full",
+	"56000baseSR4-full",
+	"56000baseLR4-full",
+	"25000baseCR-full",
+	"25000baseKR-full",
+	"25000baseSR-full",
+	"50000baseCR2-full",
+	"50000baseKR2-full",
+	"100000baseKR4-full",
+	"100000baseSR4-full",
+	"100000baseCR4-full",
+	"100000baseLR4-ER4-full",
+	"50000baseSR2-full",
+	"1000baseX-full",
+	"10000baseCR-full",
+	"10000baseSR-full",
+	"10000baseLR-full",
+	"10000baseLRM-full",
+	"10000baseER-full",
+	"2500baseT-full",
+	"5000baseT-full",
+	"fec-none",
+	"fec-rs",
+	"fec-baser",
+	"50000baseKR-full",
+	"50000baseSR-full",
+	"50000baseCR-full",
+	"50000baseLR-ER-FR-full",
+	"50000baseDR-full",
+	"100000baseKR2-full",
+	"100000baseSR2-full",
+	"100000baseCR2-full",
+	"100000baseLR2-ER2-FR2-full",
+	"100000baseDR2-full",
+	"200000baseKR4-full",
+	"200000baseSR4-full",
+	"200000baseLR4-ER4-FR4-full",
+	"200000baseDR4-full",
+	"200000baseCR4-full",
+	"100baseT1-full",
+	"1000baseT1-full",
+	"400000baseKR8-full",
+	"400000baseSR8-full",
+	"400000baseLR8-ER8-FR8-full",
+	"400000baseDR8-full",
+	"400000baseCR8-full",
+	"fec-llrs",
+	"100000baseKR-full",
+	"100000baseSR-full",
+	"100000baseLR-ER-FR-full",
+	"100000baseCR-full",
+	"100000baseDR-full",
+	"200000baseKR2-full",
+	"200000baseSR2-full",
+	"200000baseLR2-ER2-FR2-full",
+	"200000baseDR2-full",
+	"200000baseCR2-full",
+	"400000baseKR4-full",
+	"400000baseSR4-full",
+	"400000baseLR4-ER4-FR4-full",
+	"400000baseDR4-full",
+	"400000baseCR4-full",
+	"100baseFX-half",
+	"100baseFX-full",
+}
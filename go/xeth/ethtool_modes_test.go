@@ -0,0 +1,78 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+)
+
+func TestEthtoolLinkModeBits(t *testing.T) {
+	var modes xeth.EthtoolLinkModeBits
+	modes = modes.Set(1, 64, 100, 191)
+	if len(modes) != 3 {
+		t.Fatal("len", len(modes))
+	}
+	for bit, expect := range map[uint]bool{
+		0: false, 1: true, 63: false, 64: true, 100: true,
+		191: true, 192: false, 500: false,
+	} {
+		if modes.Test(bit) != expect {
+			t.Error("test", bit)
+		}
+	}
+	if bits := modes.Bits(); !reflect.DeepEqual(bits, []uint{1, 64, 100, 191}) {
+		t.Error("bits", bits)
+	}
+	if n := modes.Count(); n != 4 {
+		t.Error("count", n)
+	}
+	if bit, ok := modes.Highest(); !ok || bit != 191 {
+		t.Error("highest", bit, ok)
+	}
+	modes = modes.Clear(64, 191, 500)
+	if bits := modes.Bits(); !reflect.DeepEqual(bits, []uint{1, 100}) {
+		t.Error("clear", bits)
+	}
+	// the cleared high word remains, but it's insignificant
+	if len(modes) != 3 ||
+		!modes.Equal(xeth.EthtoolLinkModeBits{}.Set(1, 100)) {
+		t.Error("equal", modes)
+	}
+	and := modes.And(xeth.EthtoolLinkModeBits{}.Set(100, 150))
+	if bits := and.Bits(); !reflect.DeepEqual(bits, []uint{100}) {
+		t.Error("and", bits)
+	}
+	or := modes.Or(xeth.EthtoolLinkModeBits{}.Set(150))
+	if bits := or.Bits(); !reflect.DeepEqual(bits, []uint{1, 100, 150}) {
+		t.Error("or", bits)
+	}
+	if bits := modes.Bits(); !reflect.DeepEqual(bits, []uint{1, 100}) {
+		t.Error("or changed", bits)
+	}
+}
+
+func TestLinkModesCopy(t *testing.T) {
+	l := new(xeth.Link)
+	modes := xeth.EthtoolLinkModeBits{}.Set(3, 100)
+	l.LinkModesSupported(modes)
+	modes.Clear(100)
+	got := l.LinkModesSupported()
+	got.Set(4).Clear(3)
+	if bits := l.LinkModesSupported().Bits(); !reflect.DeepEqual(bits,
+		[]uint{3, 100}) {
+		t.Error("supported", bits)
+	}
+	eee := xeth.EthtoolEee{Advertised: xeth.EthtoolLinkModeBits{}.Set(5)}
+	l.EthtoolEee(eee)
+	eee.Advertised.Clear(5)
+	l.EthtoolEee().Advertised.Set(6)
+	if bits := l.EthtoolEee().Advertised.Bits(); !reflect.DeepEqual(bits,
+		[]uint{5}) {
+		t.Error("eee", bits)
+	}
+}
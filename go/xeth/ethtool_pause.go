@@ -0,0 +1,54 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"context"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// EthtoolPause is the flow control of ethtool -A.
+type EthtoolPause struct {
+	AutoNeg bool
+	Rx      bool
+	Tx      bool
+}
+
+type DevEthtoolPause struct {
+	Xid
+	EthtoolPause
+}
+
+func (xid Xid) RxEthtoolPause(msg *internal.MsgEthtoolPause) *DevEthtoolPause {
+	dev := &DevEthtoolPause{xid, EthtoolPause{
+		AutoNeg: msg.Autoneg != 0,
+		Rx:      msg.Rx_pause != 0,
+		Tx:      msg.Tx_pause != 0,
+	}}
+	if l := LinkOf(xid); l != nil {
+		l.EthtoolPause(dev.EthtoolPause)
+	}
+	return dev
+}
+
+// Send the negotiated pause to driver through hi-priority channel.
+func (task *Task) SetPause(ctx context.Context, xid Xid, pause EthtoolPause) error {
+	buf := newBuffer(internal.SizeofMsgEthtoolPause)
+	msg := (*internal.MsgEthtoolPause)(buf.pointer())
+	msg.Header.Set(internal.MsgKindEthtoolPause)
+	msg.Xid = uint32(xid)
+	msg.Autoneg = boolByte(pause.AutoNeg)
+	msg.Rx_pause = boolByte(pause.Rx)
+	msg.Tx_pause = boolByte(pause.Tx)
+	return task.hi(ctx, buf)
+}
+
+func boolByte(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
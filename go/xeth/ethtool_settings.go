@@ -9,15 +9,94 @@ import "github.com/platinasystems/xeth/v3/go/xeth/internal"
 type AutoNeg uint8
 type Duplex uint8
 type DevPort uint8
+type EthTpMdix uint8
+type MdioSupport uint8
 
-type DevEthtoolSettings Xid
+// EthtoolSettings are the link settings sent by the driver.
+type EthtoolSettings struct {
+	Speed         uint32 // Mbps
+	Duplex        Duplex
+	Port          DevPort
+	PhyAddress    uint8
+	AutoNeg       AutoNeg
+	MdioSupport   MdioSupport
+	EthTpMdix     EthTpMdix // status
+	EthTpMdixCtrl EthTpMdix // control
+}
+
+// EthtoolSettingsFields flag the members of EthtoolSettings.
+type EthtoolSettingsFields uint8
+
+const (
+	EthtoolSettingsSpeed EthtoolSettingsFields = 1 << iota
+	EthtoolSettingsDuplex
+	EthtoolSettingsPort
+	EthtoolSettingsPhyAddress
+	EthtoolSettingsAutoNeg
+	EthtoolSettingsMdioSupport
+	EthtoolSettingsEthTpMdix
+	EthtoolSettingsEthTpMdixCtrl
+)
+
+// DevEthtoolSettings notes the link's new settings and the fields that
+// changed from the previous; all fields change with the first. Like the
+// other notes of a link, it's a value rather than a pointer.
+type DevEthtoolSettings struct {
+	Xid
+	EthtoolSettings
+	Changed EthtoolSettingsFields
+}
 
 func (xid Xid) RxEthtoolSettings(msg *internal.MsgEthtoolSettings) DevEthtoolSettings {
+	dev := DevEthtoolSettings{
+		Xid: xid,
+		EthtoolSettings: EthtoolSettings{
+			Speed:         msg.Speed,
+			Duplex:        Duplex(msg.Duplex),
+			Port:          DevPort(msg.Port),
+			PhyAddress:    msg.Phy_address,
+			AutoNeg:       AutoNeg(msg.Autoneg),
+			MdioSupport:   MdioSupport(msg.Mdio_support),
+			EthTpMdix:     EthTpMdix(msg.Eth_tp_mdix),
+			EthTpMdixCtrl: EthTpMdix(msg.Eth_tp_mdix_ctrl),
+		},
+		Changed: ^EthtoolSettingsFields(0),
+	}
 	if l := LinkOf(xid); l != nil {
-		l.EthtoolSpeed(msg.Speed)
-		l.EthtoolAutoNeg(AutoNeg(msg.Autoneg))
-		l.EthtoolDuplex(Duplex(msg.Duplex))
-		l.EthtoolDevPort(DevPort(msg.Port))
+		if _, ok := l.Load(LinkAttrEthtoolSettings); ok {
+			dev.Changed = l.EthtoolSettings().Diff(dev.EthtoolSettings)
+		}
+		l.EthtoolSettings(dev.EthtoolSettings)
 	}
-	return DevEthtoolSettings(xid)
+	return dev
+}
+
+// Diff returns the fields that differ.
+func (settings EthtoolSettings) Diff(other EthtoolSettings) (fields EthtoolSettingsFields) {
+	for _, t := range []struct {
+		differ bool
+		field  EthtoolSettingsFields
+	}{
+		{settings.Speed != other.Speed, EthtoolSettingsSpeed},
+		{settings.Duplex != other.Duplex, EthtoolSettingsDuplex},
+		{settings.Port != other.Port, EthtoolSettingsPort},
+		{settings.PhyAddress != other.PhyAddress,
+			EthtoolSettingsPhyAddress},
+		{settings.AutoNeg != other.AutoNeg, EthtoolSettingsAutoNeg},
+		{settings.MdioSupport != other.MdioSupport,
+			EthtoolSettingsMdioSupport},
+		{settings.EthTpMdix != other.EthTpMdix,
+			EthtoolSettingsEthTpMdix},
+		{settings.EthTpMdixCtrl != other.EthTpMdixCtrl,
+			EthtoolSettingsEthTpMdixCtrl},
+	} {
+		if t.differ {
+			fields |= t.field
+		}
+	}
+	return
+}
+
+func (fields EthtoolSettingsFields) Has(field EthtoolSettingsFields) bool {
+	return fields&field == field
 }
@@ -0,0 +1,73 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"testing"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+func testEthtoolSettings(xid Xid, settings EthtoolSettings) buffer {
+	buf := newBuffer(internal.SizeofMsgEthtoolSettings)
+	b := buf.bytes()
+	for i := range b {
+		b[i] = 0
+	}
+	msg := (*internal.MsgEthtoolSettings)(buf.pointer())
+	msg.Header.Set(internal.MsgKindEthtoolSettings)
+	msg.Xid = uint32(xid)
+	msg.Speed = settings.Speed
+	msg.Duplex = uint8(settings.Duplex)
+	msg.Port = uint8(settings.Port)
+	msg.Phy_address = settings.PhyAddress
+	msg.Autoneg = uint8(settings.AutoNeg)
+	msg.Mdio_support = uint8(settings.MdioSupport)
+	msg.Eth_tp_mdix = uint8(settings.EthTpMdix)
+	msg.Eth_tp_mdix_ctrl = uint8(settings.EthTpMdixCtrl)
+	return buf
+}
+
+func TestEthtoolSettingsChanged(t *testing.T) {
+	const xid = Xid(301)
+	Pool(Parse(testIfInfo(xid, 1, internal.IfInfoReasonNew)))
+	defer RxDelete(xid)
+	settings := EthtoolSettings{
+		Speed:   10000,
+		Duplex:  DUPLEX_FULL,
+		AutoNeg: AUTONEG_DISABLE,
+	}
+	changed := func(settings EthtoolSettings) EthtoolSettingsFields {
+		t.Helper()
+		switch dev := Parse(testEthtoolSettings(xid, settings)).(type) {
+		case DevEthtoolSettings:
+			if dev.Xid != xid || dev.EthtoolSettings != settings {
+				t.Error("note", dev)
+			}
+			cached := LinkOf(xid).EthtoolSettings()
+			if cached != settings {
+				t.Error("cached", cached)
+			}
+			return dev.Changed
+		default:
+			t.Fatalf("%T", dev)
+		}
+		return 0
+	}
+	if fields := changed(settings); fields != ^EthtoolSettingsFields(0) {
+		t.Error("first", fields)
+	}
+	if fields := changed(settings); fields != 0 {
+		t.Error("same", fields)
+	}
+	settings.Speed = 25000
+	settings.AutoNeg = AUTONEG_ENABLE
+	fields := changed(settings)
+	if fields != EthtoolSettingsSpeed|EthtoolSettingsAutoNeg ||
+		!fields.Has(EthtoolSettingsSpeed) ||
+		fields.Has(EthtoolSettingsDuplex) {
+		t.Error("speed and autoneg", fields)
+	}
+}
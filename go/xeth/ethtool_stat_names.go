@@ -0,0 +1,177 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+const (
+	siocethtool      = 0x8946
+	ethtoolGStrings  = 0x1b
+	ethtoolGSsetInfo = 0x37
+	ethSsStats       = 1
+	ethGStringLen    = 32
+)
+
+// The mux's ethtool stat names, indexed from a port proxy by the first
+// SetEthtoolStatByName after each connect.
+type statNames struct {
+	mutex sync.Mutex
+	names []string
+	index map[string]uint32
+}
+
+type ifreqData struct {
+	name [syscall.IFNAMSIZ]byte
+	data uintptr
+	_    [16]byte
+}
+
+type ethtoolSsetInfo struct {
+	cmd      uint32
+	reserved uint32
+	mask     uint64
+	count    uint32
+}
+
+// EthtoolStatNames of the named netdev in the task's network namespace.
+func EthtoolStatNames(ifname string) ([]string, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+	info := ethtoolSsetInfo{
+		cmd:  ethtoolGSsetInfo,
+		mask: 1 << ethSsStats,
+	}
+	if err = ethtoolIoctl(fd, ifname, unsafe.Pointer(&info)); err != nil {
+		return nil, fmt.Errorf("%s: %w", ifname, err)
+	}
+	if info.mask == 0 || info.count == 0 {
+		return []string{}, nil
+	}
+	n := int(info.count)
+	buf := make([]byte, 12+n*ethGStringLen)
+	*(*[3]uint32)(unsafe.Pointer(&buf[0])) =
+		[3]uint32{ethtoolGStrings, ethSsStats, uint32(n)}
+	if err = ethtoolIoctl(fd, ifname, unsafe.Pointer(&buf[0])); err != nil {
+		return nil, fmt.Errorf("%s: %w", ifname, err)
+	}
+	if got := int(*(*uint32)(unsafe.Pointer(&buf[8]))); got < n {
+		n = got
+	}
+	names := make([]string, n)
+	for i := range names {
+		s := buf[12+i*ethGStringLen : 12+(i+1)*ethGStringLen]
+		if nul := bytes.IndexByte(s, 0); nul >= 0 {
+			s = s[:nul]
+		}
+		names[i] = string(s)
+	}
+	return names, nil
+}
+
+func ethtoolIoctl(fd int, ifname string, data unsafe.Pointer) error {
+	var ifr ifreqData
+	if len(ifname) >= len(ifr.name) {
+		return syscall.EINVAL
+	}
+	copy(ifr.name[:], ifname)
+	ifr.data = uintptr(data)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd),
+		siocethtool, uintptr(unsafe.Pointer(&ifr)))
+	runtime.KeepAlive(data)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Send the ethtool stat of the given name to driver in a batch of latest
+// stats. The names are loaded from the port proxy with the lowest xid in the
+// default network namespace and stored in each port's StatNames.
+func (task *Task) SetEthtoolStatByName(ctx context.Context, xid Xid,
+	name string, n uint64) error {
+	index, err := task.ethtoolStatIndex(xid, name)
+	if err != nil {
+		return err
+	}
+	return task.setStat(ctx, internal.MsgKindEthtoolStat, xid, index, n)
+}
+
+func (task *Task) ethtoolStatIndex(xid Xid, name string) (uint32, error) {
+	s := &task.statNames
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.index == nil {
+		names, err := loadEthtoolStatNames()
+		if err != nil {
+			return 0, err
+		}
+		s.names = names
+		s.index = make(map[string]uint32, len(names))
+		for i, name := range names {
+			s.index[name] = uint32(i)
+		}
+	}
+	index, found := s.index[name]
+	if !found {
+		return 0, fmt.Errorf("%v: unknown ethtool stat %q", xid, name)
+	}
+	if l := LinkOf(xid); l != nil && l.StatNames() == nil {
+		l.StatNames(s.names)
+	}
+	return index, nil
+}
+
+// forget the names so that the next SetEthtoolStatByName reloads them
+func (s *statNames) reset() {
+	s.mutex.Lock()
+	s.names = nil
+	s.index = nil
+	s.mutex.Unlock()
+}
+
+func loadEthtoolStatNames() ([]string, error) {
+	proxy := statNamesProxy()
+	if proxy == nil {
+		return nil, fmt.Errorf("no port for ethtool stat names")
+	}
+	names, err := EthtoolStatNames(proxy.IfInfoName())
+	if err != nil {
+		return nil, err
+	}
+	LinkRange(func(xid Xid, l *Link) bool {
+		if l.IsPort() {
+			l.StatNames(names)
+		}
+		return true
+	})
+	return names, nil
+}
+
+// The port in the default network namespace with the lowest xid; LinkRange
+// is unordered so the first found would differ from run to run.
+func statNamesProxy() (proxy *Link) {
+	var lowest Xid
+	LinkRange(func(xid Xid, l *Link) bool {
+		if l.IsPort() && l.IfInfoNetNs() == DefaultNetNs &&
+			(proxy == nil || xid < lowest) {
+			proxy, lowest = l, xid
+		}
+		return true
+	})
+	return
+}
@@ -0,0 +1,35 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"testing"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+func TestStatNamesProxy(t *testing.T) {
+	if proxy := statNamesProxy(); proxy != nil {
+		t.Skip("cached port", proxy.IfInfoName())
+	}
+	// the lowest, 1, isn't in the default network namespace
+	for _, xid := range []Xid{9, 4, 1, 7, 2, 5} {
+		netns := DefaultNetNs
+		if xid == 1 {
+			netns = 2
+		}
+		Pool(Parse(testIfInfo(xid, netns, internal.IfInfoReasonNew)))
+		defer RxDelete(xid)
+	}
+	for i := 0; i < 16; i++ {
+		proxy := statNamesProxy()
+		if proxy == nil {
+			t.Fatal("no proxy")
+		}
+		if name := proxy.IfInfoName(); name != "xeth2" {
+			t.Fatal("proxy", name)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// the zeroed message of the given kind from the driver
+func testEthtoolMsg(kind uint8, n int) (buffer, unsafe.Pointer) {
+	buf := newBuffer(n)
+	b := buf.bytes()
+	for i := range b {
+		b[i] = 0
+	}
+	(*internal.MsgHeader)(buf.pointer()).Set(kind)
+	return buf, buf.pointer()
+}
+
+func TestEthtoolPause(t *testing.T) {
+	const xid = Xid(311)
+	Pool(Parse(testIfInfo(xid, 1, internal.IfInfoReasonNew)))
+	defer RxDelete(xid)
+	buf, p := testEthtoolMsg(internal.MsgKindEthtoolPause,
+		internal.SizeofMsgEthtoolPause)
+	msg := (*internal.MsgEthtoolPause)(p)
+	msg.Xid = uint32(xid)
+	msg.Autoneg = 1
+	msg.Tx_pause = 1
+	want := EthtoolPause{AutoNeg: true, Tx: true}
+	switch dev := Parse(buf).(type) {
+	case *DevEthtoolPause:
+		if dev.Xid != xid || dev.EthtoolPause != want {
+			t.Error("note", dev)
+		}
+	default:
+		t.Fatalf("%T", dev)
+	}
+	if pause := LinkOf(xid).EthtoolPause(); pause != want {
+		t.Error("cached", pause)
+	}
+}
+
+func TestEthtoolFec(t *testing.T) {
+	const xid = Xid(312)
+	Pool(Parse(testIfInfo(xid, 1, internal.IfInfoReasonNew)))
+	defer RxDelete(xid)
+	buf, p := testEthtoolMsg(internal.MsgKindEthtoolFec,
+		internal.SizeofMsgEthtoolFec)
+	msg := (*internal.MsgEthtoolFec)(p)
+	msg.Xid = uint32(xid)
+	msg.Fec = 1 << 3 // ETHTOOL_FEC_RS
+	want := EthtoolFec{Configured: 1 << 3}
+	switch dev := Parse(buf).(type) {
+	case *DevEthtoolFec:
+		if dev.Xid != xid || dev.EthtoolFec != want ||
+			!dev.Configured.Has(1<<3) || dev.Configured.Has(1<<4) {
+			t.Error("note", dev)
+		}
+	default:
+		t.Fatalf("%T", dev)
+	}
+	if fec := LinkOf(xid).EthtoolFec(); fec != want {
+		t.Error("cached", fec)
+	}
+}
+
+func TestEthtoolEee(t *testing.T) {
+	const xid = Xid(313)
+	Pool(Parse(testIfInfo(xid, 1, internal.IfInfoReasonNew)))
+	defer RxDelete(xid)
+	buf, p := testEthtoolMsg(internal.MsgKindEthtoolEee,
+		internal.SizeofMsgEthtoolEee)
+	msg := (*internal.MsgEthtoolEee)(p)
+	msg.Xid = uint32(xid)
+	msg.Advertised = 1<<5 | 1<<12
+	msg.Tx_lpi_timer = 100
+	msg.Eee_enabled = 1
+	msg.Tx_lpi_enabled = 1
+	want := EthtoolEee{
+		Supported:    EthtoolLinkModeBits{0},
+		Advertised:   EthtoolLinkModeBits{1<<5 | 1<<12},
+		LPAdvertised: EthtoolLinkModeBits{0},
+		TxLpiTimer:   100,
+		Enabled:      true,
+		TxLpiEnabled: true,
+	}
+	switch dev := Parse(buf).(type) {
+	case *DevEthtoolEee:
+		if dev.Xid != xid || !reflect.DeepEqual(dev.EthtoolEee, want) {
+			t.Errorf("note %+v", dev)
+		}
+	default:
+		t.Fatalf("%T", dev)
+	}
+	if eee := LinkOf(xid).EthtoolEee(); !reflect.DeepEqual(eee, want) {
+		t.Errorf("cached %+v", eee)
+	}
+}
@@ -29,6 +29,8 @@ type FibEntry struct {
 	Rtn
 	Tos uint8
 	Ref
+
+	dumpgen uint64
 }
 
 type NH struct {
@@ -89,6 +91,33 @@ func (fe *FibEntry) Pool() {
 	poolFibEntry.Put(fe)
 }
 
+// clone an uncached copy
+func (fe *FibEntry) clone() *FibEntry {
+	clone := newFibEntry()
+	clone.IPNet.IP = clone.IPNet.IP[:len(fe.IPNet.IP)]
+	clone.IPNet.Mask = clone.IPNet.Mask[:len(fe.IPNet.Mask)]
+	copy(clone.IPNet.IP, fe.IPNet.IP)
+	copy(clone.IPNet.Mask, fe.IPNet.Mask)
+	for _, nh := range fe.NHs {
+		clonh := newNH()
+		clonh.IP = clonh.IP[:len(nh.IP)]
+		copy(clonh.IP, nh.IP)
+		clonh.Xid = nh.Xid
+		clonh.Ifindex = nh.Ifindex
+		clonh.Weight = nh.Weight
+		clonh.RtnhFlags = nh.RtnhFlags
+		clonh.RtScope = nh.RtScope
+		clone.NHs = append(clone.NHs, clonh)
+	}
+	clone.NetNs = fe.NetNs
+	clone.RtTable = fe.RtTable
+	clone.FibEntryEvent = fe.FibEntryEvent
+	clone.Rtn = fe.Rtn
+	clone.Tos = fe.Tos
+	clone.dumpgen = fe.dumpgen
+	return clone
+}
+
 func (nh *NH) Pool() {
 	nh.IP = nh.IP[:net.IPv6len]
 	poolNH.Put(nh)
@@ -129,6 +158,7 @@ func fib4(msg *internal.MsgFibEntry) *FibEntry {
 	fe.Rtn = Rtn(msg.Type)
 	fe.RtTable = RtTable(msg.Table)
 	fe.Tos = msg.Tos
+	fe.dumpgen = fibGeneration()
 	for _, nh := range msg.NextHops() {
 		xid := fe.NetNs.Xid(nh.Ifindex)
 		fenh := newNH()
@@ -154,6 +184,7 @@ func fib6(msg *internal.MsgFib6Entry) *FibEntry {
 	fe.FibEntryEvent = FibEntryEvent(msg.Event)
 	fe.Rtn = Rtn(msg.Type)
 	fe.RtTable = RtTable(msg.Table)
+	fe.dumpgen = fibGeneration()
 	nhxid := netns.Xid(msg.Nh.Ifindex)
 	nh := newNH()
 	copy(nh.IP, msg.Nh.Gw[:])
@@ -109,7 +109,7 @@ const (
 )
 
 const (
-	LinkStatRxPackets	= iota
+	LinkStatRxPackets	LinkStat	= iota
 	LinkStatTxPackets
 	LinkStatRxBytes
 	LinkStatTxBytes
@@ -135,7 +135,9 @@ const (
 	LinkStatRxNohandler
 )
 
-var IndexofLinkStat = map[string]int{
+const NLinkStat = 0x18
+
+var IndexofLinkStat = map[string]LinkStat{
 	"rx-packets":		LinkStatRxPackets,
 	"tx-packets":		LinkStatTxPackets,
 	"rx-bytes":		LinkStatRxBytes,
@@ -192,6 +194,20 @@ const (
 	ETH_TP_MDI_AUTO		= 3
 )
 
+const (
+	ETH_MDIO_SUPPORTS_C22	= 1
+	ETH_MDIO_SUPPORTS_C45	= 2
+)
+
+const (
+	ETHTOOL_FEC_NONE	= 1 << 0x0
+	ETHTOOL_FEC_AUTO	= 1 << 0x1
+	ETHTOOL_FEC_OFF		= 1 << 0x2
+	ETHTOOL_FEC_RS		= 1 << 0x3
+	ETHTOOL_FEC_BASER	= 1 << 0x4
+	ETHTOOL_FEC_LLRS	= 1 << 0x5
+)
+
 const (
 	ETHTOOL_LINK_MODE_10baseT_Half_BIT		= 0
 	ETHTOOL_LINK_MODE_10baseT_Full_BIT		= 1
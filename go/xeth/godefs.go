@@ -119,7 +119,7 @@ const (
 )
 
 const (
-	LinkStatRxPackets = iota
+	LinkStatRxPackets LinkStat = iota
 	LinkStatTxPackets
 	LinkStatRxBytes
 	LinkStatTxBytes
@@ -145,7 +145,9 @@ const (
 	LinkStatRxNohandler
 )
 
-var IndexofLinkStat = map[string]int{
+const NLinkStat = C.XETH_N_LINK_STAT
+
+var IndexofLinkStat = map[string]LinkStat{
 	"rx-packets":          LinkStatRxPackets,
 	"tx-packets":          LinkStatTxPackets,
 	"rx-bytes":            LinkStatRxBytes,
@@ -202,6 +204,20 @@ const (
 	ETH_TP_MDI_AUTO    = 3 //                  control: auto-select
 )
 
+const (
+	ETH_MDIO_SUPPORTS_C22 = 1
+	ETH_MDIO_SUPPORTS_C45 = 2
+)
+
+const (
+	ETHTOOL_FEC_NONE  = 1 << C.XETH_FEC_NONE_BIT
+	ETHTOOL_FEC_AUTO  = 1 << C.XETH_FEC_AUTO_BIT
+	ETHTOOL_FEC_OFF   = 1 << C.XETH_FEC_OFF_BIT
+	ETHTOOL_FEC_RS    = 1 << C.XETH_FEC_RS_BIT
+	ETHTOOL_FEC_BASER = 1 << C.XETH_FEC_BASER_BIT
+	ETHTOOL_FEC_LLRS  = 1 << C.XETH_FEC_LLRS_BIT
+)
+
 const (
 	ETHTOOL_LINK_MODE_10baseT_Half_BIT           = 0
 	ETHTOOL_LINK_MODE_10baseT_Full_BIT           = 1
@@ -6,6 +6,7 @@ package xeth
 
 import (
 	"net"
+	"sync/atomic"
 
 	"github.com/platinasystems/xeth/v3/go/xeth/internal"
 )
@@ -28,7 +29,9 @@ func RxIfInfo(msg *internal.MsgIfInfo) (note interface{}) {
 		l = new(Link)
 		l.xid = xid
 		Links.Store(xid, l)
+		deletedNetNs.Delete(xid)
 	}
+	atomic.StoreUint64(&l.dumpgen, ifinfoGeneration())
 	l.IfInfoKdata(msg.Kdata)
 	if len(l.IfInfoName()) == 0 {
 		note = DevNew(xid)
@@ -28,6 +28,16 @@ func (kind MsgKind) String() string {
 		MsgKindFibEntry:                      "fib-entry",
 		MsgKindNeighUpdate:                   "neighbor-update",
 		MsgKindChangeUpperXid:                "change-upper",
+		MsgKindEthtoolPause:                  "ethtool-pause",
+		MsgKindEthtoolFec:                    "ethtool-fec",
+		MsgKindEthtoolEee:                    "ethtool-eee",
+		MsgKindDisconnected:                  "disconnected",
+		MsgKindResynced:                      "resynced",
+		MsgKindMarkIfInfo:                    "mark-ifinfo",
+		MsgKindMarkFib:                       "mark-fib",
+		MsgKindOverrun:                       "overrun",
+		MsgKindFrame:                         "frame",
+		MsgKindCarrierDampened:               "carrier-dampened",
 	}[kind]
 	if !found {
 		s = fmt.Sprint(uint8(kind))
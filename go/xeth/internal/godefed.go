@@ -49,6 +49,33 @@ type MsgEthtoolLinkModes struct {
 	Reserved	uint32
 	Modes		uint64
 }
+type MsgEthtoolPause struct {
+	Header		MsgHeader
+	Xid		uint32
+	Autoneg		uint8
+	Rx_pause	uint8
+	Tx_pause	uint8
+	Pad		uint8
+}
+type MsgEthtoolFec struct {
+	Header		MsgHeader
+	Xid		uint32
+	Fec		uint32
+	Active_fec	uint32
+	Reserved	uint32
+}
+type MsgEthtoolEee struct {
+	Header		MsgHeader
+	Xid		uint32
+	Supported	uint32
+	Advertised	uint32
+	Lp_advertised	uint32
+	Tx_lpi_timer	uint32
+	Eee_active	uint8
+	Eee_enabled	uint8
+	Tx_lpi_enabled	uint8
+	Pad		uint8
+}
 type NextHop struct {
 	Ifindex	int32
 	Weight	int32
@@ -163,6 +190,9 @@ const (
 	MsgKindChangeUpperXid			= 0x12
 	MsgKindNetNsAdd				= 0x13
 	MsgKindNetNsDel				= 0x14
+	MsgKindEthtoolPause			= 0x15
+	MsgKindEthtoolFec			= 0x16
+	MsgKindEthtoolEee			= 0x17
 )
 
 const (
@@ -175,6 +205,9 @@ const (
 	SizeofMsgEthtoolFlags		= 0x18
 	SizeofMsgEthtoolSettings	= 0x20
 	SizeofMsgEthtoolLinkModes	= 0x20
+	SizeofMsgEthtoolPause		= 0x18
+	SizeofMsgEthtoolFec		= 0x20
+	SizeofMsgEthtoolEee		= 0x28
 	SizeofMsgIfa			= 0x20
 	SizeofMsgIfa6			= 0x30
 	SizeofMsgIfInfo			= 0x48
@@ -188,7 +221,10 @@ const (
 	SizeofMsgStat			= 0x20
 )
 
-const MsgVersion = 0x2
+const (
+	MinMsgVersion	= 0x2
+	MsgVersion	= 0x3
+)
 
 const (
 	SizeofIfName		= 0x10
@@ -18,6 +18,9 @@ type MsgChangeUpperXid C.struct_xeth_msg_change_upper_xid
 type MsgEthtoolFlags C.struct_xeth_msg_ethtool_flags
 type MsgEthtoolSettings C.struct_xeth_msg_ethtool_settings
 type MsgEthtoolLinkModes C.struct_xeth_msg_ethtool_link_modes
+type MsgEthtoolPause C.struct_xeth_msg_ethtool_pause
+type MsgEthtoolFec C.struct_xeth_msg_ethtool_fec
+type MsgEthtoolEee C.struct_xeth_msg_ethtool_eee
 type NextHop C.struct_xeth_next_hop
 type MsgFibEntry C.struct_xeth_msg_fibentry
 type NextHop6 C.struct_xeth_next_hop6
@@ -52,6 +55,9 @@ const (
 	MsgKindChangeUpperXid                = C.XETH_MSG_KIND_CHANGE_UPPER_XID
 	MsgKindNetNsAdd                      = C.XETH_MSG_KIND_NETNS_ADD
 	MsgKindNetNsDel                      = C.XETH_MSG_KIND_NETNS_DEL
+	MsgKindEthtoolPause                  = C.XETH_MSG_KIND_ETHTOOL_PAUSE
+	MsgKindEthtoolFec                    = C.XETH_MSG_KIND_ETHTOOL_FEC
+	MsgKindEthtoolEee                    = C.XETH_MSG_KIND_ETHTOOL_EEE
 )
 
 const (
@@ -64,6 +70,9 @@ const (
 	SizeofMsgEthtoolFlags     = C.sizeof_struct_xeth_msg_ethtool_flags
 	SizeofMsgEthtoolSettings  = C.sizeof_struct_xeth_msg_ethtool_settings
 	SizeofMsgEthtoolLinkModes = C.sizeof_struct_xeth_msg_ethtool_link_modes
+	SizeofMsgEthtoolPause     = C.sizeof_struct_xeth_msg_ethtool_pause
+	SizeofMsgEthtoolFec       = C.sizeof_struct_xeth_msg_ethtool_fec
+	SizeofMsgEthtoolEee       = C.sizeof_struct_xeth_msg_ethtool_eee
 	SizeofMsgIfa              = C.sizeof_struct_xeth_msg_ifa
 	SizeofMsgIfa6             = C.sizeof_struct_xeth_msg_ifa6
 	SizeofMsgIfInfo           = C.sizeof_struct_xeth_msg_ifinfo
@@ -77,7 +86,10 @@ const (
 	SizeofMsgStat             = C.sizeof_struct_xeth_msg_stat
 )
 
-const MsgVersion = C.XETH_MSG_VERSION
+const (
+	MinMsgVersion = C.XETH_MSG_MIN_VERSION
+	MsgVersion    = C.XETH_MSG_VERSION
+)
 
 const (
 	SizeofIfName     = C.XETH_IFNAMSIZ
@@ -6,12 +6,32 @@ package internal
 
 import (
 	"fmt"
-	"reflect"
 	"unsafe"
 )
 
 type MsgKind uint8
 
+// Task local kinds of notes queued to its receive channel; these never pass
+// through the side-band socket. A side-band message of these kinds is
+// unknown rather than a note.
+const (
+	MsgKindDisconnected = 0xff - iota
+	MsgKindResynced
+	MsgKindMarkIfInfo
+	MsgKindMarkFib
+	MsgKindOverrun
+	// exception frames have no header; this kind only counts them
+	MsgKindFrame
+	MsgKindCarrierDampened
+
+	// the least of the above
+	MinLocalMsgKind = MsgKindCarrierDampened
+)
+
+// LocalMsgVersion distinguishes the task local notes from the side-band
+// messages, which are never version zero.
+const LocalMsgVersion = 0
+
 func (h *MsgHeader) Set(kind uint8) {
 	h.Z64 = 0
 	h.Z32 = 0
@@ -20,6 +40,27 @@ func (h *MsgHeader) Set(kind uint8) {
 	h.Kind = kind
 }
 
+// KindVersion returns the first version with the given kind of side-band
+// message; version 3 adds the ethtool pause, fec, and eee kinds.
+func KindVersion(kind uint8) uint8 {
+	switch kind {
+	case MsgKindEthtoolPause, MsgKindEthtoolFec, MsgKindEthtoolEee:
+		return 3
+	}
+	return MinMsgVersion
+}
+
+// SetLocal sets the header of a task local note.
+func (h *MsgHeader) SetLocal(kind uint8) {
+	h.Set(kind)
+	h.Version = LocalMsgVersion
+}
+
+// IsLocal is true of a task local note.
+func (h *MsgHeader) IsLocal() bool {
+	return h.Kind >= MinLocalMsgKind && h.Version == LocalMsgVersion
+}
+
 func (h *MsgHeader) Validate(buf []byte) error {
 	if len(buf) < SizeofMsg {
 		return fmt.Errorf("msg too small %v", buf)
@@ -27,16 +68,58 @@ func (h *MsgHeader) Validate(buf []byte) error {
 	if h.Z64 != 0 || h.Z32 != 0 || h.Z16 != 0 {
 		return fmt.Errorf("msg has non-zero header fields  %+v", *h)
 	}
-	if h.Version != MsgVersion {
-		return fmt.Errorf("msg version %d, expect %d",
-			h.Version, MsgVersion)
+	if h.Version < MinMsgVersion || h.Version > MsgVersion {
+		return fmt.Errorf("msg version %d, expect %d to %d",
+			h.Version, MinMsgVersion, MsgVersion)
+	}
+	if v := KindVersion(h.Kind); h.Version < v {
+		return fmt.Errorf("msg kind %d, expect version >= %d",
+			h.Kind, v)
+	}
+	// pass unsupported kinds for the user to parse or ignore
+	min, exact := lengths(buf, false)
+	return checkLen(buf, min, exact)
+}
+
+// CheckLen verifies that the buffer holds the header, fixed, and variable
+// length sections of its message kind. Unsupported kinds pass.
+func CheckLen(buf []byte) error {
+	if len(buf) < SizeofMsg {
+		return fmt.Errorf("msg length %d, expect >= %d",
+			len(buf), SizeofMsg)
+	}
+	min, exact := lengths(buf, true)
+	return checkLen(buf, min, exact)
+}
+
+func checkLen(buf []byte, min, exact int) error {
+	if min > 0 && len(buf) < min {
+		return fmt.Errorf("msg length %d, expect >= %d", len(buf), min)
+	}
+	if exact > 0 && len(buf) < exact {
+		return fmt.Errorf("msg length %d, expect %d", len(buf), exact)
 	}
-	var exact, min int
+	return nil
+}
+
+// lengths returns the minimum or exact length of the buffer's message kind;
+// with variable, the minimum includes the next hops or siblings counted by a
+// FIB entry. Both are zero for unsupported kinds.
+func lengths(buf []byte, variable bool) (min, exact int) {
+	h := (*MsgHeader)(unsafe.Pointer(&buf[0]))
 	switch h.Kind {
 	case MsgKindFibEntry:
 		min = SizeofMsgFibEntry
+		if variable && len(buf) >= min {
+			msg := (*MsgFibEntry)(unsafe.Pointer(&buf[0]))
+			min += int(msg.Nhs) * SizeofNextHop
+		}
 	case MsgKindFib6Entry:
 		min = SizeofMsgFib6Entry
+		if variable && len(buf) >= min {
+			msg := (*MsgFib6Entry)(unsafe.Pointer(&buf[0]))
+			min += int(msg.Nsiblings) * SizeofNextHop6
+		}
 	case MsgKindEthtoolLinkModesSupported,
 		MsgKindEthtoolLinkModesAdvertising,
 		MsgKindEthtoolLinkModesLPAdvertising:
@@ -61,37 +144,43 @@ func (h *MsgHeader) Validate(buf []byte) error {
 		exact = SizeofMsgNetNs
 	case MsgKindNetNsDel:
 		exact = SizeofMsgNetNs
-	default:
-		return fmt.Errorf("msg kind %d unsupported", h.Kind)
+	case MsgKindEthtoolPause:
+		exact = SizeofMsgEthtoolPause
+	case MsgKindEthtoolFec:
+		exact = SizeofMsgEthtoolFec
+	case MsgKindEthtoolEee:
+		exact = SizeofMsgEthtoolEee
 	}
-	if min > 0 && len(buf) < min {
-		return fmt.Errorf("msg length %d, expect >= %d", len(buf), min)
-	}
-	if exact > 0 && len(buf) < exact {
-		return fmt.Errorf("msg length %d, expect %d", len(buf), exact)
+	return
+}
+
+// Words of a link modes message of the given length. The driver sends one
+// word; a wider message has more words following the first.
+func (msg *MsgEthtoolLinkModes) Words(n int) []uint64 {
+	words := 1
+	if n > SizeofMsgEthtoolLinkModes {
+		words += (n - SizeofMsgEthtoolLinkModes) / 8
 	}
-	return nil
+	ptr := unsafe.Pointer(&msg.Modes)
+	return (*[1 << 16]uint64)(ptr)[:words:words]
 }
 
+// NextHops of a message that passed CheckLen.
 func (msg *MsgFibEntry) NextHops() []NextHop {
-	ptr := unsafe.Pointer(msg)
 	nhs := int(msg.Nhs)
-	return *(*[]NextHop)(unsafe.Pointer(&reflect.SliceHeader{
-		Data: uintptr(ptr) + uintptr(SizeofMsgFibEntry),
-		Len:  nhs,
-		Cap:  nhs,
-	}))
+	if nhs == 0 {
+		return []NextHop{}
+	}
+	ptr := unsafe.Pointer(uintptr(unsafe.Pointer(msg)) + SizeofMsgFibEntry)
+	return (*[1 << 8]NextHop)(ptr)[:nhs:nhs]
 }
 
+// Siblings of a message that passed CheckLen.
 func (msg *MsgFib6Entry) Siblings() []NextHop6 {
 	nsiblings := int(msg.Nsiblings)
 	if nsiblings == 0 {
 		return []NextHop6{}
 	}
-	ptr := unsafe.Pointer(msg)
-	return *(*[]NextHop6)(unsafe.Pointer(&reflect.SliceHeader{
-		Data: uintptr(ptr) + uintptr(SizeofMsgFib6Entry),
-		Len:  nsiblings,
-		Cap:  nsiblings,
-	}))
+	ptr := unsafe.Pointer(uintptr(unsafe.Pointer(msg)) + SizeofMsgFib6Entry)
+	return (*[1 << 8]NextHop6)(ptr)[:nsiblings:nsiblings]
 }
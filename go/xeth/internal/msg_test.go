@@ -0,0 +1,90 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func newMsg(kind uint8, n int) []byte {
+	b := make([]byte, n)
+	(*MsgHeader)(unsafe.Pointer(&b[0])).Set(kind)
+	return b
+}
+
+func fibEntry(nhs uint8, n int) []byte {
+	b := newMsg(MsgKindFibEntry, SizeofMsgFibEntry+n*SizeofNextHop)
+	(*MsgFibEntry)(unsafe.Pointer(&b[0])).Nhs = nhs
+	return b
+}
+
+func fib6Entry(nsiblings uint8, n int) []byte {
+	b := newMsg(MsgKindFib6Entry, SizeofMsgFib6Entry+n*SizeofNextHop6)
+	(*MsgFib6Entry)(unsafe.Pointer(&b[0])).Nsiblings = nsiblings
+	return b
+}
+
+func TestCheckLen(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		buf  []byte
+		ok   bool
+	}{
+		{"short header", make([]byte, SizeofMsg-1), false},
+		{"break", newMsg(MsgKindBreak, SizeofMsgBreak), true},
+		{"short ifinfo", newMsg(MsgKindIfInfo, SizeofMsgIfInfo-1), false},
+		{"fib no nhs", fibEntry(0, 0), true},
+		{"fib nhs", fibEntry(2, 2), true},
+		{"fib truncated nhs", fibEntry(2, 1), false},
+		{"fib short nhs", fibEntry(1, 1)[:SizeofMsgFibEntry+1], false},
+		{"fib max nhs", fibEntry(255, 254), false},
+		{"fib6 siblings", fib6Entry(2, 2), true},
+		{"fib6 truncated siblings", fib6Entry(2, 1), false},
+		{"fib6 no siblings", fib6Entry(1, 0), false},
+		{"short fib6", fib6Entry(0, 0)[:SizeofMsgFib6Entry-1], false},
+		{"unknown", newMsg(0x80, SizeofMsg), true},
+	} {
+		err := CheckLen(tc.buf)
+		if tc.ok && err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		} else if !tc.ok && err == nil {
+			t.Errorf("%s: passed", tc.name)
+		}
+	}
+}
+
+func FuzzValidate(f *testing.F) {
+	f.Add(newMsg(MsgKindBreak, SizeofMsgBreak))
+	f.Add(newMsg(MsgKindIfInfo, SizeofMsgIfInfo))
+	f.Add(fibEntry(2, 2))
+	f.Add(fib6Entry(1, 1))
+	f.Add(newMsg(MsgKindEthtoolLinkModesSupported,
+		SizeofMsgEthtoolLinkModes+8))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// like the task's receive buffer
+		var page [4096]byte
+		n := copy(page[:], b)
+		h := (*MsgHeader)(unsafe.Pointer(&page[0]))
+		if h.Validate(page[:n]) != nil || CheckLen(page[:n]) != nil {
+			return
+		}
+		// the variable sections are within the message
+		end := 0
+		switch h.Kind {
+		case MsgKindFibEntry:
+			msg := (*MsgFibEntry)(unsafe.Pointer(&page[0]))
+			end = SizeofMsgFibEntry +
+				len(msg.NextHops())*SizeofNextHop
+		case MsgKindFib6Entry:
+			msg := (*MsgFib6Entry)(unsafe.Pointer(&page[0]))
+			end = SizeofMsgFib6Entry +
+				len(msg.Siblings())*SizeofNextHop6
+		}
+		if end > n {
+			t.Fatalf("kind %d ends at %d of %d", h.Kind, end, n)
+		}
+	})
+}
@@ -31,6 +31,9 @@ var poolIPNet = sync.Pool{
 
 func (xid Xid) RxIP4Add(addr, mask uint32) *DevAddIPNet {
 	l := LinkOf(xid)
+	if l == nil {
+		return nil
+	}
 	ip := net.IP(make([]byte, net.IPv4len, net.IPv4len))
 	*(*uint32)(unsafe.Pointer(&ip[0])) = addr
 	nets := l.IPNets()
@@ -73,6 +76,9 @@ func (xid Xid) RxIP4Del(addr, mask uint32) *DevDelIPNet {
 
 func (xid Xid) RxIP6Add(addr []byte, len int) *DevAddIPNet {
 	l := LinkOf(xid)
+	if l == nil {
+		return nil
+	}
 	ip := net.IP(addr)
 	nets := l.IPNets()
 	for _, entry := range nets {
@@ -29,6 +29,10 @@ type Linker interface {
 	EthtoolDuplex(set ...Duplex) Duplex
 	EthtoolDevPort(set ...DevPort) DevPort
 	EthtoolFlags(set ...EthtoolFlagBits) EthtoolFlagBits
+	EthtoolSettings(set ...EthtoolSettings) EthtoolSettings
+	EthtoolPause(set ...EthtoolPause) EthtoolPause
+	EthtoolFec(set ...EthtoolFec) EthtoolFec
+	EthtoolEee(set ...EthtoolEee) EthtoolEee
 	EthtoolSpeed(set ...uint32) uint32
 	IfInfoKdata(set ...uint32) uint32
 	IfInfoName(set ...string) string
@@ -49,9 +53,11 @@ type Linker interface {
 	LinkModesSupported(set ...EthtoolLinkModeBits) EthtoolLinkModeBits
 	LinkModesAdvertising(set ...EthtoolLinkModeBits) EthtoolLinkModeBits
 	LinkModesLPAdvertising(set ...EthtoolLinkModeBits) EthtoolLinkModeBits
+	ResolveLinkMode() (EthtoolLinkMode, uint32, bool)
 	LinkUp(set ...bool) bool
 	Lowers(set ...[]Xid) []Xid
 	Uppers(set ...[]Xid) []Xid
+	LinkStats(set ...LinkStats) LinkStats
 	Stats(set ...[]uint64) []uint64
 	StatNames(set ...[]string) []string
 	String() string
@@ -83,15 +89,25 @@ const (
 	LinkAttrStatNames
 	LinkAttrStats
 	LinkAttrUppers
+	LinkAttrEthtoolSettings
+	LinkAttrEthtoolPause
+	LinkAttrEthtoolFec
+	LinkAttrEthtoolEee
+	LinkAttrLinkStats
 )
 
 type Link struct {
 	sync.Map
-	xid Xid
+	xid     Xid
+	dumpgen uint64
 }
 
 var Links sync.Map
 
+// the name spaces of deleted links so that FilterNetNs may match their
+// DevDel after the link is gone
+var deletedNetNs sync.Map
+
 func LinkOf(xid Xid) (l *Link) {
 	if v, ok := Links.Load(xid); ok {
 		l = v.(*Link)
@@ -128,6 +144,7 @@ func RxDelete(xid Xid) (note DevDel) {
 	if l == nil {
 		return
 	}
+	deletedNetNs.Store(xid, l.IfInfoNetNs())
 	for _, entry := range l.IPNets() {
 		entry.IP = entry.IP[:cap(entry.IP)]
 		entry.Mask = entry.Mask[:cap(entry.Mask)]
@@ -187,6 +204,58 @@ func (l *Link) EthtoolFlags(set ...EthtoolFlagBits) (bits EthtoolFlagBits) {
 	return
 }
 
+// EthtoolSettings sets or gets all of the link's settings, including the
+// speed, autoneg, duplex and port of the respective accessors.
+func (l *Link) EthtoolSettings(set ...EthtoolSettings) (settings EthtoolSettings) {
+	if len(set) > 0 {
+		settings = set[0]
+		l.Store(LinkAttrEthtoolSettings, settings)
+		l.EthtoolSpeed(settings.Speed)
+		l.EthtoolAutoNeg(settings.AutoNeg)
+		l.EthtoolDuplex(settings.Duplex)
+		l.EthtoolDevPort(settings.Port)
+		return
+	}
+	if v, ok := l.Load(LinkAttrEthtoolSettings); ok {
+		settings = v.(EthtoolSettings)
+	}
+	settings.Speed = l.EthtoolSpeed()
+	settings.AutoNeg = l.EthtoolAutoNeg()
+	settings.Duplex = l.EthtoolDuplex()
+	settings.Port = l.EthtoolDevPort()
+	return
+}
+
+func (l *Link) EthtoolPause(set ...EthtoolPause) (pause EthtoolPause) {
+	if len(set) > 0 {
+		pause = set[0]
+		l.Store(LinkAttrEthtoolPause, pause)
+	} else if v, ok := l.Load(LinkAttrEthtoolPause); ok {
+		pause = v.(EthtoolPause)
+	}
+	return
+}
+
+func (l *Link) EthtoolFec(set ...EthtoolFec) (fec EthtoolFec) {
+	if len(set) > 0 {
+		fec = set[0]
+		l.Store(LinkAttrEthtoolFec, fec)
+	} else if v, ok := l.Load(LinkAttrEthtoolFec); ok {
+		fec = v.(EthtoolFec)
+	}
+	return
+}
+
+func (l *Link) EthtoolEee(set ...EthtoolEee) (eee EthtoolEee) {
+	if len(set) > 0 {
+		eee = set[0]
+		l.Store(LinkAttrEthtoolEee, eee.copy())
+	} else if v, ok := l.Load(LinkAttrEthtoolEee); ok {
+		eee = v.(EthtoolEee).copy()
+	}
+	return
+}
+
 func (l *Link) EthtoolSpeed(set ...uint32) (mbps uint32) {
 	if len(set) > 0 {
 		mbps = set[0]
@@ -327,12 +396,21 @@ func (l *Link) LinkModesLPAdvertising(set ...EthtoolLinkModeBits) EthtoolLinkMod
 	return l.linkmodes(LinkAttrLinkModesLPAdvertising, set...)
 }
 
+// ResolveLinkMode of the link's supported, advertising and link partner
+// modes; see ResolveLinkMode.
+func (l *Link) ResolveLinkMode() (EthtoolLinkMode, uint32, bool) {
+	return ResolveLinkMode(l.LinkModesSupported(),
+		l.LinkModesAdvertising(), l.LinkModesLPAdvertising())
+}
+
+// The link keeps its own copy of the modes and returns another so that
+// the caller may Set or Clear them without a race with the receiver.
 func (l *Link) linkmodes(attr LinkAttr, set ...EthtoolLinkModeBits) (modes EthtoolLinkModeBits) {
 	if len(set) > 0 {
 		modes = set[0]
-		l.Store(attr, modes)
+		l.Store(attr, modes.Copy())
 	} else if v, ok := l.Load(attr); ok {
-		modes = v.(EthtoolLinkModeBits)
+		modes = v.(EthtoolLinkModeBits).Copy()
 	}
 	return
 }
@@ -367,6 +445,19 @@ func (l *Link) Uppers(set ...[]Xid) (xids []Xid) {
 	return
 }
 
+// LinkStats last sent to the driver.
+func (l *Link) LinkStats(set ...LinkStats) (stats LinkStats) {
+	if len(set) > 0 {
+		stats = set[0]
+		l.Store(LinkAttrLinkStats, stats)
+	} else if v, ok := l.Load(LinkAttrLinkStats); ok {
+		stats = v.(LinkStats)
+	}
+	return
+}
+
+// Ethtool stats last sent to the driver, indexed like StatNames. The
+// returned slice must not be modified.
 func (l *Link) Stats(set ...[]uint64) (stats []uint64) {
 	if len(set) > 0 {
 		stats = set[0]
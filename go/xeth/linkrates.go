@@ -0,0 +1,187 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// LinkRates keeps timestamped samples of each link's stats for the retained
+// duration and computes rates over any window within it.
+//
+//	rates := xeth.NewLinkRates(5 * time.Minute)
+//	...
+//	rates.SampleLinks()
+//	if r, ok := rates.Rate(xid, time.Minute); ok {
+//		fmt.Println(r.PerSec[xeth.LinkStatRxPackets], r.RxUtil)
+//	}
+type LinkRates struct {
+	retain time.Duration
+	mutex  sync.Mutex
+	xids   map[Xid]*rateSamples
+}
+
+// LinkRate is the average and peak per second rate of each LinkStat over
+// the sampled interval of a window. Utilization is the ratio of bits per
+// second to EthtoolSpeed, or zero if the speed is unknown.
+type LinkRate struct {
+	Xid
+	Interval   time.Duration
+	PerSec     [NLinkStat]float64
+	Peak       [NLinkStat]float64
+	RxUtil     float64
+	TxUtil     float64
+	RxPeakUtil float64
+	TxPeakUtil float64
+}
+
+type rateSamples struct {
+	t       time.Time // of the latest sample
+	stats   LinkStats
+	samples []rateSample // oldest first
+}
+
+// A sample has the elapsed time and counter deltas since the prior sample.
+type rateSample struct {
+	t     time.Time
+	dt    time.Duration
+	delta [NLinkStat]uint64
+}
+
+func NewLinkRates(retain time.Duration) *LinkRates {
+	return &LinkRates{
+		retain: retain,
+		xids:   make(map[Xid]*rateSamples),
+	}
+}
+
+// Sample the stats of xid at the given time. A sample that isn't after the
+// prior is ignored.
+func (rates *LinkRates) Sample(xid Xid, t time.Time, stats *LinkStats) {
+	rates.mutex.Lock()
+	defer rates.mutex.Unlock()
+	rs := rates.xids[xid]
+	if rs == nil {
+		rates.xids[xid] = &rateSamples{
+			t:     t,
+			stats: *stats,
+		}
+		return
+	}
+	dt := t.Sub(rs.t)
+	if dt <= 0 {
+		return
+	}
+	sample := rateSample{t: t, dt: dt}
+	prev := rs.stats.Array()
+	for i, n := range stats.Array() {
+		sample.delta[i] = counterDelta(prev[i], n)
+	}
+	rs.t = t
+	rs.stats = *stats
+	rs.samples = append(rs.samples, sample)
+	cutoff := t.Add(-rates.retain)
+	i := 0
+	for i < len(rs.samples) && !rs.samples[i].t.After(cutoff) {
+		i++
+	}
+	if i > 0 {
+		n := copy(rs.samples, rs.samples[i:])
+		rs.samples = rs.samples[:n]
+	}
+}
+
+// SampleLinks samples the LinkStats mirrored in each Link and forgets the
+// samples of deleted links.
+func (rates *LinkRates) SampleLinks() {
+	t := time.Now()
+	LinkRange(func(xid Xid, l *Link) bool {
+		stats := l.LinkStats()
+		rates.Sample(xid, t, &stats)
+		return true
+	})
+	rates.mutex.Lock()
+	for xid := range rates.xids {
+		if LinkOf(xid) == nil {
+			delete(rates.xids, xid)
+		}
+	}
+	rates.mutex.Unlock()
+}
+
+// Forget the samples of xid.
+func (rates *LinkRates) Forget(xid Xid) {
+	rates.mutex.Lock()
+	delete(rates.xids, xid)
+	rates.mutex.Unlock()
+}
+
+// Rate of xid over the window ending with its latest sample; false if there
+// are fewer than two samples within the window.
+func (rates *LinkRates) Rate(xid Xid, window time.Duration) (LinkRate, bool) {
+	r := LinkRate{Xid: xid}
+	var sum [NLinkStat]uint64
+	rates.mutex.Lock()
+	rs := rates.xids[xid]
+	if rs != nil && len(rs.samples) > 0 {
+		cutoff := rs.t.Add(-window)
+		for i := len(rs.samples) - 1; i >= 0; i-- {
+			sample := &rs.samples[i]
+			if sample.t.Add(-sample.dt).Before(cutoff) {
+				break
+			}
+			r.Interval += sample.dt
+			secs := sample.dt.Seconds()
+			for j, n := range sample.delta {
+				sum[j] += n
+				r.Peak[j] = math.Max(r.Peak[j], float64(n)/secs)
+			}
+		}
+	}
+	rates.mutex.Unlock()
+	if r.Interval == 0 {
+		return r, false
+	}
+	secs := r.Interval.Seconds()
+	for i, n := range sum {
+		r.PerSec[i] = float64(n) / secs
+	}
+	if l := LinkOf(xid); l != nil {
+		if mbps := l.EthtoolSpeed(); mbps > 0 {
+			bps := float64(mbps) * 1e6
+			r.RxUtil = 8 * r.PerSec[LinkStatRxBytes] / bps
+			r.TxUtil = 8 * r.PerSec[LinkStatTxBytes] / bps
+			r.RxPeakUtil = 8 * r.Peak[LinkStatRxBytes] / bps
+			r.TxPeakUtil = 8 * r.Peak[LinkStatTxBytes] / bps
+		}
+	}
+	return r, true
+}
+
+// Bits per second received and transmitted.
+func (r *LinkRate) RxBps() float64 { return 8 * r.PerSec[LinkStatRxBytes] }
+func (r *LinkRate) TxBps() float64 { return 8 * r.PerSec[LinkStatTxBytes] }
+
+// The increase from prev to n. A decrease is a 64-bit counter wrap if the
+// wrapped increase is less than 1<<32, e.g. from 1<<64-2 to 3 is 5; a 32-bit
+// counter wrap if prev fits 32 bits and the wrapped increase is less than
+// half that range; or otherwise a reset, so the increase is n from zero.
+func counterDelta(prev, n uint64) uint64 {
+	if n >= prev {
+		return n - prev
+	}
+	if d := n - prev; d < 1<<32 {
+		// 64-bit wrap
+		return d
+	}
+	if prev <= math.MaxUint32 {
+		if d := n + (1 << 32) - prev; d < 1<<31 {
+			return d
+		}
+	}
+	return n
+}
@@ -0,0 +1,115 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+func TestCounterDelta(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		prev, n, d uint64
+	}{
+		{"zero", 0, 0, 0},
+		{"same", 7, 7, 0},
+		{"increase", 7, 10, 3},
+		{"64-bit wrap", math.MaxUint64 - 1, 3, 5},
+		{"64-bit wrap from max", math.MaxUint64, 0, 1},
+		{"32-bit wrap", math.MaxUint32 - 1, 3, 5},
+		{"32-bit wrap from max", math.MaxUint32, 0, 1},
+		{"32-bit reset", math.MaxUint32, 1 << 31, 1 << 31},
+		{"reset", 1 << 40, 5, 5},
+		{"reset to zero", 1 << 40, 0, 0},
+		{"small reset", 10, 5, 5},
+	} {
+		if d := counterDelta(tc.prev, tc.n); d != tc.d {
+			t.Errorf("%s: %d", tc.name, d)
+		}
+	}
+}
+
+func TestLinkRate(t *testing.T) {
+	const xid = Xid(321)
+	t0 := time.Unix(1000, 0)
+	type sample struct {
+		dt      time.Duration // since t0
+		packets uint64
+	}
+	for _, tc := range []struct {
+		name    string
+		samples []sample
+		window  time.Duration
+		ok      bool
+		perSec  float64
+		peak    float64
+		span    time.Duration
+	}{
+		{"none", nil, time.Minute, false, 0, 0, 0},
+		{"one", []sample{{0, 10}}, time.Minute, false, 0, 0, 0},
+		{"zero interval", []sample{{0, 10}, {0, 20}},
+			time.Minute, false, 0, 0, 0},
+		{"steady", []sample{{0, 0}, {time.Second, 10},
+			{2 * time.Second, 20}}, time.Minute, true, 10, 10,
+			2 * time.Second},
+		{"peak", []sample{{0, 0}, {time.Second, 10},
+			{2 * time.Second, 40}}, time.Minute, true, 20, 30,
+			2 * time.Second},
+		{"window", []sample{{0, 0}, {time.Second, 100},
+			{2 * time.Second, 110}}, time.Second, true, 10, 10,
+			time.Second},
+		{"wrap", []sample{{0, math.MaxUint64 - 4},
+			{time.Second, 5}}, time.Minute, true, 10, 10,
+			time.Second},
+		{"reset", []sample{{0, 1000}, {time.Second, 5}},
+			time.Minute, true, 5, 5, time.Second},
+		{"ignored", []sample{{0, 0}, {time.Second, 10},
+			{time.Second, 99}, {500 * time.Millisecond, 99}},
+			time.Minute, true, 10, 10, time.Second},
+	} {
+		rates := NewLinkRates(time.Hour)
+		for _, s := range tc.samples {
+			stats := LinkStats{RxPackets: s.packets}
+			rates.Sample(xid, t0.Add(s.dt), &stats)
+		}
+		r, ok := rates.Rate(xid, tc.window)
+		if ok != tc.ok {
+			t.Errorf("%s: ok %v", tc.name, ok)
+			continue
+		}
+		if r.Interval != tc.span ||
+			r.PerSec[LinkStatRxPackets] != tc.perSec ||
+			r.Peak[LinkStatRxPackets] != tc.peak ||
+			r.PerSec[LinkStatTxPackets] != 0 {
+			t.Errorf("%s: %v %v %v", tc.name, r.Interval,
+				r.PerSec[LinkStatRxPackets],
+				r.Peak[LinkStatRxPackets])
+		}
+	}
+}
+
+func TestLinkRateUtil(t *testing.T) {
+	const xid = Xid(322)
+	Pool(Parse(testIfInfo(xid, 1, internal.IfInfoReasonNew)))
+	defer RxDelete(xid)
+	rates := NewLinkRates(time.Hour)
+	t0 := time.Unix(1000, 0)
+	rates.Sample(xid, t0, &LinkStats{})
+	// 125 MB/s, a gigabit
+	rates.Sample(xid, t0.Add(time.Second), &LinkStats{RxBytes: 125e6})
+	r, ok := rates.Rate(xid, time.Minute)
+	if !ok || r.RxBps() != 1e9 || r.RxUtil != 0 {
+		t.Error("unknown speed", r.RxBps(), r.RxUtil, ok)
+	}
+	LinkOf(xid).EthtoolSpeed(10000)
+	r, ok = rates.Rate(xid, time.Minute)
+	if !ok || r.RxUtil != 0.1 || r.RxPeakUtil != 0.1 || r.TxUtil != 0 {
+		t.Error("util", r.RxUtil, r.RxPeakUtil, r.TxUtil, ok)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"context"
+	"unsafe"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// LinkStats mirrors rtnl_link_stats64; its fields are in LinkStat order.
+type LinkStats struct {
+	RxPackets         uint64
+	TxPackets         uint64
+	RxBytes           uint64
+	TxBytes           uint64
+	RxErrors          uint64
+	TxErrors          uint64
+	RxDropped         uint64
+	TxDropped         uint64
+	Multicast         uint64
+	Collisions        uint64
+	RxLengthErrors    uint64
+	RxOverErrors      uint64
+	RxCrcErrors       uint64
+	RxFrameErrors     uint64
+	RxFifoErrors      uint64
+	RxMissedErrors    uint64
+	TxAbortedErrors   uint64
+	TxCarrierErrors   uint64
+	TxFifoErrors      uint64
+	TxHeartbeatErrors uint64
+	TxWindowErrors    uint64
+	RxCompressed      uint64
+	TxCompressed      uint64
+	RxNohandler       uint64
+}
+
+// fails to compile if LinkStats has other than NLinkStat counters
+var _ = [1]struct{}{}[unsafe.Sizeof(LinkStats{})-NLinkStat*8]
+
+// Array of the stats indexed by LinkStat.
+func (stats *LinkStats) Array() *[NLinkStat]uint64 {
+	return (*[NLinkStat]uint64)(unsafe.Pointer(stats))
+}
+
+// Send the link stats that changed since the last SetLinkStats of xid in a
+// batch of latest stats. The first of each xid, and the first after a
+// reconnect, sends them all.
+func (task *Task) SetLinkStats(ctx context.Context, xid Xid,
+	stats *LinkStats) error {
+	if err := task.stopped(ctx); err != nil {
+		return err
+	}
+	s := &task.stats
+	s.mutex.Lock()
+	last, found := s.links[xid]
+	if !found {
+		last = new(LinkStats)
+		s.links[xid] = last
+	}
+	changed := false
+	prev := last.Array()
+	for i, n := range stats.Array() {
+		if !found || n != prev[i] {
+			s.set(internal.MsgKindLinkStat, xid, uint32(i), n)
+			changed = true
+		}
+	}
+	*last = *stats
+	s.mutex.Unlock()
+	if changed {
+		s.signal()
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics of a task's messages and exception frames by kind.
+type Metrics struct {
+	Rx         [1 << 8]Counter // received from driver
+	Tx         [1 << 8]Counter // sent to driver
+	Invalid    [1 << 8]Counter // received but failed validation
+	Reconnects Counter
+
+	// carrier changes not sent to driver by the task's dampening
+	CarrierSuppressed Counter
+
+	// exception frames dropped by the kernel with a full raw ring
+	RingDropped Counter
+
+	lastRx, lastTx int64 // unix nanoseconds
+
+	task *Task
+}
+
+// Depths of a task's queues.
+type Depths struct {
+	RxCh  int // received messages and frames waiting for the consumer
+	Lo    int // low priority tx channel
+	Hi    int // high priority tx channel
+	Stats int // pending stats
+}
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (task *Task) Metrics() *Metrics {
+	return &task.metrics
+}
+
+// LastRx returns the time of the last received message or frame.
+func (m *Metrics) LastRx() time.Time {
+	return unixNano(atomic.LoadInt64(&m.lastRx))
+}
+
+// LastTx returns the time of the last sent message or frame.
+func (m *Metrics) LastTx() time.Time {
+	return unixNano(atomic.LoadInt64(&m.lastTx))
+}
+
+func (m *Metrics) Depths() Depths {
+	task := m.task
+	task.stats.mutex.Lock()
+	stats := len(task.stats.keys)
+	task.stats.mutex.Unlock()
+	return Depths{
+		RxCh:  len(task.rxch),
+		Lo:    len(task.loch),
+		Hi:    len(task.hich),
+		Stats: stats,
+	}
+}
+
+func (m *Metrics) rx(kind MsgKind) {
+	m.Rx[kind].Inc()
+	atomic.StoreInt64(&m.lastRx, time.Now().UnixNano())
+}
+
+func (m *Metrics) tx(kind MsgKind) {
+	m.Tx[kind].Inc()
+	atomic.StoreInt64(&m.lastTx, time.Now().UnixNano())
+}
+
+// MetricsHandler serves the package counters and the metrics of the given
+// tasks in OpenMetrics text format.
+func MetricsHandler(tasks ...*Task) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", openMetricsContentType)
+		WriteMetrics(w, tasks...)
+	})
+}
+
+// WriteMetrics writes the package counters and the metrics of the given
+// tasks in OpenMetrics text format. Task samples are labeled by mux and,
+// where applicable, message kind; kinds without samples are omitted.
+func WriteMetrics(w io.Writer, tasks ...*Task) error {
+	bw := bufio.NewWriter(w)
+	family := func(name, typ, help string) {
+		fmt.Fprintf(bw, "# TYPE %s %s\n# HELP %s %s\n",
+			name, typ, name, help)
+	}
+	byKind := func(name, help string,
+		count func(task *Task, kind MsgKind) uint64) {
+		family(name, "counter", help)
+		for _, task := range tasks {
+			for kind := 0; kind < 1<<8; kind++ {
+				n := count(task, MsgKind(kind))
+				if n == 0 {
+					continue
+				}
+				fmt.Fprintf(bw,
+					"%s_total{mux=\"%s\",kind=\"%s\"} %d\n",
+					name, label(task.mux), label(MsgKind(kind)), n)
+			}
+		}
+	}
+	for _, global := range []struct {
+		name, help string
+		*Counter
+	}{
+		{"xeth_cloned", "Cloned received messages.", &Cloned},
+		{"xeth_parsed", "Messages parsed by user.", &Parsed},
+		{"xeth_dropped", "Messages dropped before sent to driver.",
+			&Dropped},
+		{"xeth_sent", "Messages and frames sent to driver.", &Sent},
+		{"xeth_unknown", "Lookups of xids without ifinfo.", &Unknown},
+		{"xeth_coalesced", "Stats superseded before sent to driver.",
+			&Coalesced},
+	} {
+		family(global.name, "counter", global.help)
+		fmt.Fprintf(bw, "%s_total %d\n", global.name, global.Count())
+	}
+	byKind("xeth_rx_messages", "Messages and frames received by kind.",
+		func(task *Task, kind MsgKind) uint64 {
+			return task.metrics.Rx[kind].Count()
+		})
+	byKind("xeth_tx_messages", "Messages and frames sent by kind.",
+		func(task *Task, kind MsgKind) uint64 {
+			return task.metrics.Tx[kind].Count()
+		})
+	byKind("xeth_rx_invalid", "Received messages that failed validation.",
+		func(task *Task, kind MsgKind) uint64 {
+			return task.metrics.Invalid[kind].Count()
+		})
+	byKind("xeth_rx_dropped", "Received messages dropped by overflow.",
+		func(task *Task, kind MsgKind) uint64 {
+			return task.RxDropped(kind)
+		})
+	family("xeth_reconnects", "counter", "Reconnects after mux down.")
+	for _, task := range tasks {
+		fmt.Fprintf(bw, "xeth_reconnects_total{mux=\"%s\"} %d\n",
+			label(task.mux), task.metrics.Reconnects.Count())
+	}
+	family("xeth_carrier_suppressed", "counter",
+		"Carrier changes dampened before sent to driver.")
+	for _, task := range tasks {
+		fmt.Fprintf(bw,
+			"xeth_carrier_suppressed_total{mux=\"%s\"} %d\n",
+			label(task.mux), task.metrics.CarrierSuppressed.Count())
+	}
+	family("xeth_ring_dropped", "counter",
+		"Exception frames dropped by a full raw ring.")
+	for _, task := range tasks {
+		fmt.Fprintf(bw, "xeth_ring_dropped_total{mux=\"%s\"} %d\n",
+			label(task.mux), task.metrics.RingDropped.Count())
+	}
+	family("xeth_queue_depth", "gauge", "Entries waiting in task queues.")
+	for _, task := range tasks {
+		depths := task.metrics.Depths()
+		for _, q := range []struct {
+			name  string
+			depth int
+		}{
+			{"rx", depths.RxCh},
+			{"lo", depths.Lo},
+			{"hi", depths.Hi},
+			{"stats", depths.Stats},
+		} {
+			fmt.Fprintf(bw,
+				"xeth_queue_depth{mux=\"%s\",queue=\"%s\"} %d\n",
+				label(task.mux), q.name, q.depth)
+		}
+	}
+	for _, last := range []struct {
+		name, help string
+		t          func(m *Metrics) time.Time
+	}{
+		{"xeth_last_rx_timestamp_seconds",
+			"Time of the last received message or frame.",
+			(*Metrics).LastRx},
+		{"xeth_last_tx_timestamp_seconds",
+			"Time of the last sent message or frame.",
+			(*Metrics).LastTx},
+	} {
+		family(last.name, "gauge", last.help)
+		for _, task := range tasks {
+			t := last.t(&task.metrics)
+			if t.IsZero() {
+				continue
+			}
+			fmt.Fprintf(bw, "%s{mux=\"%s\"} %.3f\n", last.name,
+				label(task.mux), float64(t.UnixNano())/1e9)
+		}
+	}
+	fmt.Fprintln(bw, "# EOF")
+	return bw.Flush()
+}
+
+// escape a label value as OpenMetrics requires
+func label(v interface{}) string {
+	return labelEscaper.Replace(fmt.Sprint(v))
+}
+
+func unixNano(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
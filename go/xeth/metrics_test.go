@@ -0,0 +1,90 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+const metricsGolden = `# TYPE xeth_cloned counter
+# HELP xeth_cloned Cloned received messages.
+xeth_cloned_total 1
+# TYPE xeth_parsed counter
+# HELP xeth_parsed Messages parsed by user.
+xeth_parsed_total 2
+# TYPE xeth_dropped counter
+# HELP xeth_dropped Messages dropped before sent to driver.
+xeth_dropped_total 3
+# TYPE xeth_sent counter
+# HELP xeth_sent Messages and frames sent to driver.
+xeth_sent_total 4
+# TYPE xeth_unknown counter
+# HELP xeth_unknown Lookups of xids without ifinfo.
+xeth_unknown_total 5
+# TYPE xeth_coalesced counter
+# HELP xeth_coalesced Stats superseded before sent to driver.
+xeth_coalesced_total 6
+# TYPE xeth_rx_messages counter
+# HELP xeth_rx_messages Messages and frames received by kind.
+xeth_rx_messages_total{mux="a\"b\\c\nd",kind="break"} 7
+# TYPE xeth_tx_messages counter
+# HELP xeth_tx_messages Messages and frames sent by kind.
+xeth_tx_messages_total{mux="a\"b\\c\nd",kind="carrier"} 8
+# TYPE xeth_rx_invalid counter
+# HELP xeth_rx_invalid Received messages that failed validation.
+# TYPE xeth_rx_dropped counter
+# HELP xeth_rx_dropped Received messages dropped by overflow.
+xeth_rx_dropped_total{mux="a\"b\\c\nd",kind="ifinfo"} 9
+# TYPE xeth_reconnects counter
+# HELP xeth_reconnects Reconnects after mux down.
+xeth_reconnects_total{mux="a\"b\\c\nd"} 10
+# TYPE xeth_carrier_suppressed counter
+# HELP xeth_carrier_suppressed Carrier changes dampened before sent to driver.
+xeth_carrier_suppressed_total{mux="a\"b\\c\nd"} 0
+# TYPE xeth_ring_dropped counter
+# HELP xeth_ring_dropped Exception frames dropped by a full raw ring.
+xeth_ring_dropped_total{mux="a\"b\\c\nd"} 0
+# TYPE xeth_queue_depth gauge
+# HELP xeth_queue_depth Entries waiting in task queues.
+xeth_queue_depth{mux="a\"b\\c\nd",queue="rx"} 0
+xeth_queue_depth{mux="a\"b\\c\nd",queue="lo"} 0
+xeth_queue_depth{mux="a\"b\\c\nd",queue="hi"} 0
+xeth_queue_depth{mux="a\"b\\c\nd",queue="stats"} 0
+# TYPE xeth_last_rx_timestamp_seconds gauge
+# HELP xeth_last_rx_timestamp_seconds Time of the last received message or frame.
+xeth_last_rx_timestamp_seconds{mux="a\"b\\c\nd"} 1600000000.250
+# TYPE xeth_last_tx_timestamp_seconds gauge
+# HELP xeth_last_tx_timestamp_seconds Time of the last sent message or frame.
+# EOF
+`
+
+func TestWriteMetrics(t *testing.T) {
+	for i, c := range []*Counter{
+		&Cloned, &Parsed, &Dropped, &Sent, &Unknown, &Coalesced,
+	} {
+		defer func(c *Counter, n uint64) {
+			c.Reset()
+			c.Add(n)
+		}(c, c.Count())
+		c.Reset()
+		c.Add(uint64(i + 1))
+	}
+	task := &Task{mux: "a\"b\\c\nd"}
+	task.metrics.task = task
+	task.metrics.Rx[MsgKindBreak].Add(7)
+	task.metrics.Tx[MsgKindCarrier].Add(8)
+	task.overrun.total[MsgKindIfInfo] = 9
+	task.metrics.Reconnects.Add(10)
+	task.metrics.lastRx = time.Unix(1600000000, 250000000).UnixNano()
+	w := new(bytes.Buffer)
+	if err := WriteMetrics(w, task); err != nil {
+		t.Fatal(err)
+	}
+	if s := w.String(); s != metricsGolden {
+		t.Errorf("got\n%s", s)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import "github.com/platinasystems/xeth/v3/go/xeth/internal"
+
+// MsgKind identifies side-band messages, task local notes, and exception
+// frames for the task's accounting.
+type MsgKind uint8
+
+const (
+	MsgKindBreak                         MsgKind = internal.MsgKindBreak
+	MsgKindLinkStat                      MsgKind = internal.MsgKindLinkStat
+	MsgKindEthtoolStat                   MsgKind = internal.MsgKindEthtoolStat
+	MsgKindEthtoolFlags                  MsgKind = internal.MsgKindEthtoolFlags
+	MsgKindEthtoolSettings               MsgKind = internal.MsgKindEthtoolSettings
+	MsgKindEthtoolLinkModesSupported     MsgKind = internal.MsgKindEthtoolLinkModesSupported
+	MsgKindEthtoolLinkModesAdvertising   MsgKind = internal.MsgKindEthtoolLinkModesAdvertising
+	MsgKindEthtoolLinkModesLPAdvertising MsgKind = internal.MsgKindEthtoolLinkModesLPAdvertising
+	MsgKindDumpIfInfo                    MsgKind = internal.MsgKindDumpIfInfo
+	MsgKindCarrier                       MsgKind = internal.MsgKindCarrier
+	MsgKindSpeed                         MsgKind = internal.MsgKindSpeed
+	MsgKindIfInfo                        MsgKind = internal.MsgKindIfInfo
+	MsgKindIfa                           MsgKind = internal.MsgKindIfa
+	MsgKindIfa6                          MsgKind = internal.MsgKindIfa6
+	MsgKindDumpFibInfo                   MsgKind = internal.MsgKindDumpFibInfo
+	MsgKindFibEntry                      MsgKind = internal.MsgKindFibEntry
+	MsgKindFib6Entry                     MsgKind = internal.MsgKindFib6Entry
+	MsgKindNeighUpdate                   MsgKind = internal.MsgKindNeighUpdate
+	MsgKindChangeUpperXid                MsgKind = internal.MsgKindChangeUpperXid
+	MsgKindNetNsAdd                      MsgKind = internal.MsgKindNetNsAdd
+	MsgKindNetNsDel                      MsgKind = internal.MsgKindNetNsDel
+	MsgKindEthtoolPause                  MsgKind = internal.MsgKindEthtoolPause
+	MsgKindEthtoolFec                    MsgKind = internal.MsgKindEthtoolFec
+	MsgKindEthtoolEee                    MsgKind = internal.MsgKindEthtoolEee
+
+	MsgKindDisconnected MsgKind = internal.MsgKindDisconnected
+	MsgKindResynced     MsgKind = internal.MsgKindResynced
+	MsgKindMarkIfInfo   MsgKind = internal.MsgKindMarkIfInfo
+	MsgKindMarkFib      MsgKind = internal.MsgKindMarkFib
+	MsgKindOverrun      MsgKind = internal.MsgKindOverrun
+	MsgKindFrame        MsgKind = internal.MsgKindFrame
+
+	MsgKindCarrierDampened MsgKind = internal.MsgKindCarrierDampened
+)
+
+// KindOf returns the kind of a received buffer, or zero if it's too short
+// for a header.
+func KindOf(buf Buffer) MsgKind {
+	if isFrame(buf) {
+		return MsgKindFrame
+	}
+	if len(buf.bytes()) < internal.SizeofMsg {
+		return 0
+	}
+	return MsgKind(kind(buf))
+}
+
+// a side-band message has a header with leading zeros where a frame has
+// its destination and source addresses
+func isFrame(buf buffer) bool {
+	b := buf.bytes()
+	if len(b) < 14 {
+		return false
+	}
+	for _, b := range b[:14] {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -44,6 +44,27 @@ func (neigh *Neighbor) Pool() {
 	}
 }
 
+// clone an uncached copy
+func (neigh *Neighbor) clone() *Neighbor {
+	clone := newNeighbor()
+	clone.NetNs = neigh.NetNs
+	clone.Xid = neigh.Xid
+	clone.IP = clone.IP[:len(neigh.IP)]
+	copy(clone.IP, neigh.IP)
+	copy(clone.HardwareAddr, neigh.HardwareAddr)
+	return clone
+}
+
+// a zero hardware address deletes the neighbor
+func (neigh *Neighbor) isDel() bool {
+	for _, b := range neigh.HardwareAddr {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // to sort a list of neighbors,
 //	sort.Slice(neighbors, func(i, j int) bool {
 //		return neighbors[i].Less(neighbors[j])
@@ -61,7 +82,11 @@ func neighbor(msg *internal.MsgNeighUpdate) *Neighbor {
 		copy(neigh.IP, msg.Dst[:net.IPv4len])
 		neigh.IP = neigh.IP[:net.IPv4len]
 	} else {
-		copy(neigh.IP, msg.Dst[:int(msg.Len)])
+		n := int(msg.Len)
+		if n > len(msg.Dst) {
+			n = len(msg.Dst)
+		}
+		copy(neigh.IP, msg.Dst[:n])
 		neigh.IP = neigh.IP[:net.IPv6len]
 	}
 	copy(neigh.HardwareAddr, msg.Lladdr[:])
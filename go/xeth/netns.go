@@ -294,14 +294,12 @@ func (ns NetNs) fibentry(fe *FibEntry) {
 func (ns NetNs) neighbor(neigh *Neighbor) {
 	attrs := ns.attrs()
 	sip := neigh.IP.String()
-	for _, b := range neigh.HardwareAddr {
-		if b == 0 {
-			if v, ok := attrs.neigbors.Load(sip); ok {
-				attrs.neigbors.Delete(sip)
-				v.(*Neighbor).Pool()
-			}
-			return
+	if neigh.isDel() {
+		if v, ok := attrs.neigbors.Load(sip); ok {
+			attrs.neigbors.Delete(sip)
+			v.(*Neighbor).Pool()
 		}
+		return
 	}
 	neigh.Hold()
 	if v, ok := attrs.neigbors.Load(sip); ok {
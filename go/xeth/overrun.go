@@ -0,0 +1,226 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"sync"
+
+	"github.com/platinasystems/xeth/v3/go/endian"
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// RxPolicy selects what the task does with a received message or frame
+// when RxCh is full.
+type RxPolicy uint8
+
+const (
+	// RxBlock waits for the consumer, stalling the side-band socket.
+	RxBlock RxPolicy = iota
+	// RxDropOldest drops the oldest queued message or frame other than
+	// a Break, Mark, or Disconnected note.
+	RxDropOldest
+	// RxDropLowValue drops exception frames and ethtool flags, settings,
+	// link modes, pause, fec and eee; but blocks on all other kinds.
+	RxDropLowValue
+)
+
+// Overrun notes the messages and frames dropped by kind since the previous
+// overrun. The consumer should resync with DumpIfInfo and DumpFib.
+// This note is queued before any message received after the drops.
+type Overrun struct {
+	Dropped map[MsgKind]uint64
+}
+
+type overrun struct {
+	mutex   sync.Mutex
+	pending bool
+	dropped [1 << 8]uint64 // since the last overrun note
+	total   [1 << 8]uint64
+
+	// serializes the sends of RxDropOldest so that none are queued
+	// between those requeued behind a dropped buffer
+	rotate sync.Mutex
+	kept   []buffer
+}
+
+const sizeofMsgOverrun = internal.SizeofMsg + (1<<8)*8
+
+// RxOverflow sets the policy for a full RxCh, default RxBlock.
+func RxOverflow(policy RxPolicy) TaskOption {
+	return func(task *Task) {
+		task.rxpolicy = policy
+	}
+}
+
+// RxDropped returns the number of received messages or frames of the given
+// kind that were dropped by the task's RxPolicy.
+func (task *Task) RxDropped(kind MsgKind) uint64 {
+	task.overrun.mutex.Lock()
+	defer task.overrun.mutex.Unlock()
+	return task.overrun.total[kind]
+}
+
+func (kind MsgKind) lowValue() bool {
+	switch kind {
+	case MsgKindFrame,
+		MsgKindEthtoolFlags,
+		MsgKindEthtoolSettings,
+		MsgKindEthtoolLinkModesSupported,
+		MsgKindEthtoolLinkModesAdvertising,
+		MsgKindEthtoolLinkModesLPAdvertising,
+		MsgKindEthtoolPause,
+		MsgKindEthtoolFec,
+		MsgKindEthtoolEee:
+		return true
+	}
+	return false
+}
+
+// the buffers that pair marks and breaks are never dropped
+func essential(buf buffer) bool {
+	switch KindOf(buf) {
+	case MsgKindBreak:
+		return true
+	case MsgKindMarkIfInfo,
+		MsgKindMarkFib,
+		MsgKindDisconnected:
+		return isLocal(buf)
+	}
+	return false
+}
+
+// send a received buffer to RxCh per the task's policy; returns false if
+// the task is done.
+func (task *Task) send(buf buffer) bool {
+	if task.rxpolicy == RxDropOldest {
+		task.overrun.rotate.Lock()
+		defer task.overrun.rotate.Unlock()
+	}
+	for {
+		if !task.sendOverrun() {
+			select {
+			case task.rxch <- buf:
+				return true
+			default:
+			}
+		}
+		switch task.rxpolicy {
+		case RxDropOldest:
+			if task.dropOldest() {
+				continue
+			}
+		case RxDropLowValue:
+			if KindOf(buf).lowValue() {
+				task.drop(buf)
+				return true
+			}
+		}
+		select {
+		case task.rxch <- buf:
+			return true
+		case <-task.ctx.Done():
+			buf.pool()
+			return false
+		}
+	}
+}
+
+// drop the oldest queued buffer that isn't essential and requeue those
+// before it behind the rest; returns false if all are essential.
+func (task *Task) dropOldest() (dropped bool) {
+	kept := task.overrun.kept[:0]
+	defer func() { task.overrun.kept = kept[:0] }()
+	for n := len(task.rxch); n > 0; n-- {
+		var old buffer
+		select {
+		case old = <-task.rxch:
+		default:
+		}
+		if old == nil {
+			// the consumer emptied RxCh
+			break
+		}
+		if !dropped && !essential(old) {
+			task.drop(old)
+			dropped = true
+			if len(kept) == 0 {
+				return
+			}
+		} else {
+			kept = append(kept, old)
+		}
+	}
+	// the consumer may only have made more room
+	for _, old := range kept {
+		task.rxch <- old
+	}
+	return
+}
+
+func (task *Task) drop(buf buffer) {
+	kind := KindOf(buf)
+	task.overrun.mutex.Lock()
+	defer task.overrun.mutex.Unlock()
+	task.overrun.pending = true
+	if kind == MsgKindOverrun {
+		// fold the dropped note into the next
+		b := buf.bytes()[internal.SizeofMsg:]
+		for i := range task.overrun.dropped {
+			task.overrun.dropped[i] += endian.Host.Uint64(b[8*i:])
+		}
+	} else {
+		task.overrun.dropped[kind]++
+		task.overrun.total[kind]++
+	}
+	buf.pool()
+}
+
+// try to queue the pending overrun note with room for the buffer that
+// follows it; returns true if still pending
+func (task *Task) sendOverrun() bool {
+	task.overrun.mutex.Lock()
+	defer task.overrun.mutex.Unlock()
+	if !task.overrun.pending {
+		return false
+	}
+	if cap(task.rxch) > 1 && cap(task.rxch)-len(task.rxch) < 2 {
+		// rather than a note displacing the previous
+		return true
+	}
+	buf := newBuffer(sizeofMsgOverrun)
+	h := (*internal.MsgHeader)(buf.pointer())
+	h.SetLocal(internal.MsgKindOverrun)
+	b := buf.bytes()[internal.SizeofMsg:]
+	for i, n := range task.overrun.dropped {
+		endian.Host.PutUint64(b[8*i:], n)
+	}
+	select {
+	case task.rxch <- buf:
+		if task.rec != nil {
+			task.rec.Msg(buf.bytes(), DirectionIn)
+		}
+		task.overrun.pending = false
+		task.overrun.dropped = [1 << 8]uint64{}
+		return false
+	default:
+		buf.pool()
+		return true
+	}
+}
+
+func rxOverrun(buf buffer) Overrun {
+	note := Overrun{make(map[MsgKind]uint64)}
+	b := buf.bytes()
+	if len(b) < sizeofMsgOverrun {
+		return note
+	}
+	b = b[internal.SizeofMsg:]
+	for kind := 0; kind < 1<<8; kind++ {
+		if n := endian.Host.Uint64(b[8*kind:]); n > 0 {
+			note.Dropped[MsgKind(kind)] = n
+		}
+	}
+	return note
+}
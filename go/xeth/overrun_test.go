@@ -0,0 +1,50 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/xethtest"
+)
+
+func TestRxDropOldest(t *testing.T) {
+	mux, task := startTask(t, xeth.RxDepth(4),
+		xeth.RxOverflow(xeth.RxDropOldest))
+	var msgs [][]byte
+	for xid := xeth.Xid(61); xid <= 80; xid++ {
+		msgs = append(msgs, xethtest.MsgIfInfo(xid,
+			fmt.Sprint("xeth", xid), int32(xid), xeth.DevKindPort,
+			xethtest.ReasonDump, testHa))
+	}
+	mux.IfInfo(msgs...)
+	if err := task.DumpIfInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// let the dump overrun RxCh before reading any
+	eventually(t, func() bool {
+		return task.Metrics().Rx[xeth.MsgKindBreak].Count() == 1 &&
+			len(task.RxCh) == cap(task.RxCh)
+	})
+	var marked bool
+	var dropped uint64
+	for _, note := range untilBreak(t, task) {
+		switch note := note.(type) {
+		case xeth.Mark:
+			marked = true
+		case xeth.Overrun:
+			dropped += note.Dropped[xeth.MsgKindIfInfo]
+		}
+	}
+	if !marked {
+		t.Error("dropped mark")
+	}
+	if dropped == 0 || dropped != task.RxDropped(xeth.MsgKindIfInfo) {
+		t.Error("dropped", dropped, task.RxDropped(xeth.MsgKindIfInfo))
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"testing"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+func FuzzParse(f *testing.F) {
+	seed := func(kind uint8, n int) []byte {
+		buf := newBuffer(n)
+		defer buf.pool()
+		b := buf.bytes()
+		for i := range b {
+			b[i] = 0
+		}
+		(*internal.MsgHeader)(buf.pointer()).Set(kind)
+		return append([]byte{}, b...)
+	}
+	f.Add(seed(internal.MsgKindBreak, internal.SizeofMsgBreak))
+	f.Add(seed(internal.MsgKindIfInfo, internal.SizeofMsgIfInfo))
+	f.Add(seed(internal.MsgKindFibEntry,
+		internal.SizeofMsgFibEntry+internal.SizeofNextHop))
+	f.Add(seed(internal.MsgKindFib6Entry,
+		internal.SizeofMsgFib6Entry+internal.SizeofNextHop6))
+	f.Add(seed(internal.MsgKindEthtoolLinkModesSupported,
+		internal.SizeofMsgEthtoolLinkModes+8))
+	f.Add(seed(internal.MsgKindNeighUpdate, internal.SizeofMsgNeighUpdate))
+	f.Add(seed(internal.MsgKindEthtoolPause,
+		internal.SizeofMsgEthtoolPause))
+	f.Add(seed(internal.MsgKindEthtoolFec, internal.SizeofMsgEthtoolFec))
+	f.Add(seed(internal.MsgKindEthtoolEee, internal.SizeofMsgEthtoolEee))
+	f.Add(seed(internal.MsgKindChangeUpperXid,
+		internal.SizeofMsgChangeUpperXid))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		if len(b) >= internal.SizeofJumboFrame {
+			return
+		}
+		// notes may refer to the cache, so aren't pooled
+		Parse(cloneBuffer(b))
+	})
+}
@@ -0,0 +1,408 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/platinasystems/xeth/v3/go/endian"
+)
+
+// A Recorder writes a pcapng capture of a task's side-band messages and
+// exception frames. Side-band messages, including the task local notes
+// like Mark and Disconnected, are recorded on interface 0 with LinkTypeXeth;
+// exception frames are recorded on interface 1 with the ethernet link type.
+// Each packet has the time of its receipt or transmission and the inbound
+// or outbound direction flag. Packets are buffered, so Close the recorder to
+// write the last of them.
+type Recorder struct {
+	mutex sync.Mutex
+	w     *bufio.Writer
+	c     io.Closer // of the writer, if any
+	err   error
+	buf   []byte
+}
+
+// A Replay reads a capture written by a Recorder.
+type Replay struct {
+	r      io.Reader
+	order  binary.ByteOrder
+	ifaces []replayIface
+}
+
+// Recorded is a message or frame read from a Replay.
+type Recorded struct {
+	Buffer
+	Direction
+	Time  time.Time
+	Frame bool
+}
+
+type Direction uint8
+
+type replayIface struct {
+	linkType uint16
+	tsresol  uint8
+}
+
+// LinkTypeXeth is the pcapng link type of the recorded side-band messages,
+// LINKTYPE_USER0.
+const LinkTypeXeth = 147
+
+const (
+	DirectionIn  Direction = 1
+	DirectionOut Direction = 2
+)
+
+const (
+	linkTypeEthernet = 1
+
+	pcapngSHB   = 0x0a0d0d0a
+	pcapngIDB   = 1
+	pcapngEPB   = 6
+	pcapngMagic = 0x1a2b3c4d
+
+	pcapngOptEnd     = 0
+	pcapngOptIfName  = 2
+	pcapngOptTsresol = 9
+	pcapngOptFlags   = 2
+
+	pcapngIfaceMsg   = 0
+	pcapngIfaceFrame = 1
+
+	pcapngMaxBlock = 1 << 20
+)
+
+var ErrPcapng = errors.New("invalid pcapng")
+
+var errRecorderClosed = errors.New("closed recorder")
+
+// Record tees the task's received and sent side-band messages and exception
+// frames to the given recorder.
+func Record(rec *Recorder) TaskOption {
+	return func(task *Task) {
+		task.rec = rec
+	}
+}
+
+// NewRecorder writes the section header and interface descriptions then
+// returns a Recorder of the remaining packets. The caller should Close the
+// recorder after the recorded task.
+func NewRecorder(w io.Writer) (*Recorder, error) {
+	rec := &Recorder{w: bufio.NewWriter(w)}
+	rec.c, _ = w.(io.Closer)
+	rec.shb()
+	rec.idb(LinkTypeXeth, "xeth")
+	rec.idb(linkTypeEthernet, "exception")
+	if err := rec.flush(); err != nil {
+		return nil, err
+	}
+	if err := rec.w.Flush(); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Close writes the buffered packets then closes the writer if it's an
+// io.Closer.
+func (rec *Recorder) Close() error {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	err := rec.err
+	if err == nil {
+		err = rec.w.Flush()
+	}
+	if rec.c != nil {
+		if cerr := rec.c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err == errRecorderClosed {
+		return nil
+	}
+	rec.err = errRecorderClosed
+	return err
+}
+
+// Err returns the first write error, after which the recorder drops packets.
+// A closed recorder also drops packets.
+func (rec *Recorder) Err() error {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	return rec.err
+}
+
+// Msg records a side-band message.
+func (rec *Recorder) Msg(b []byte, dir Direction) error {
+	return rec.epb(pcapngIfaceMsg, b, dir, time.Now())
+}
+
+// Frame records an exception frame.
+func (rec *Recorder) Frame(b []byte, dir Direction) error {
+	return rec.epb(pcapngIfaceFrame, b, dir, time.Now())
+}
+
+func (rec *Recorder) shb() {
+	const n = 28
+	rec.u32(pcapngSHB)
+	rec.u32(n)
+	rec.u32(pcapngMagic)
+	rec.u16(1)
+	rec.u16(0)
+	// unspecified section length
+	rec.u32(math.MaxUint32)
+	rec.u32(math.MaxUint32)
+	rec.u32(n)
+}
+
+func (rec *Recorder) idb(linkType uint16, name string) {
+	n := 20 + 4 + pad4(len(name)) + 8 + 4
+	rec.u32(pcapngIDB)
+	rec.u32(uint32(n))
+	rec.u16(linkType)
+	rec.u16(0)
+	rec.u32(0)
+	rec.opt(pcapngOptIfName, []byte(name))
+	// nanosecond timestamps
+	rec.opt(pcapngOptTsresol, []byte{9})
+	rec.opt(pcapngOptEnd, nil)
+	rec.u32(uint32(n))
+}
+
+func (rec *Recorder) epb(iface uint32, b []byte, dir Direction,
+	t time.Time) error {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	if rec.err != nil {
+		return rec.err
+	}
+	n := 28 + pad4(len(b)) + 8 + 4 + 4
+	ts := uint64(t.UnixNano())
+	rec.u32(pcapngEPB)
+	rec.u32(uint32(n))
+	rec.u32(iface)
+	rec.u32(uint32(ts >> 32))
+	rec.u32(uint32(ts))
+	rec.u32(uint32(len(b)))
+	rec.u32(uint32(len(b)))
+	rec.bytes(b)
+	flags := make([]byte, 4)
+	endian.Host.PutUint32(flags, uint32(dir))
+	rec.opt(pcapngOptFlags, flags)
+	rec.opt(pcapngOptEnd, nil)
+	rec.u32(uint32(n))
+	return rec.flush()
+}
+
+func (rec *Recorder) opt(code uint16, b []byte) {
+	rec.u16(code)
+	rec.u16(uint16(len(b)))
+	rec.bytes(b)
+}
+
+func (rec *Recorder) u16(u uint16) {
+	rec.buf = append(rec.buf, 0, 0)
+	endian.Host.PutUint16(rec.buf[len(rec.buf)-2:], u)
+}
+
+func (rec *Recorder) u32(u uint32) {
+	rec.buf = append(rec.buf, 0, 0, 0, 0)
+	endian.Host.PutUint32(rec.buf[len(rec.buf)-4:], u)
+}
+
+func (rec *Recorder) bytes(b []byte) {
+	rec.buf = append(rec.buf, b...)
+	for i := len(b); i < pad4(len(b)); i++ {
+		rec.buf = append(rec.buf, 0)
+	}
+}
+
+func (rec *Recorder) flush() error {
+	_, err := rec.w.Write(rec.buf)
+	rec.buf = rec.buf[:0]
+	if err != nil {
+		rec.err = err
+	}
+	return err
+}
+
+func NewReplay(r io.Reader) *Replay {
+	return &Replay{r: r}
+}
+
+// Next returns the next recorded message or frame; or io.EOF at the end of
+// the capture. The caller should Parse or Pool the buffer.
+func (rp *Replay) Next() (Recorded, error) {
+	for {
+		typ, body, err := rp.block()
+		if err != nil {
+			return Recorded{}, err
+		}
+		switch typ {
+		case pcapngIDB:
+			if len(body) < 8 {
+				return Recorded{}, ErrPcapng
+			}
+			iface := replayIface{
+				linkType: rp.order.Uint16(body),
+				tsresol:  6,
+			}
+			rp.options(body[8:], func(code uint16, b []byte) {
+				if code == pcapngOptTsresol && len(b) > 0 {
+					iface.tsresol = b[0]
+				}
+			})
+			rp.ifaces = append(rp.ifaces, iface)
+		case pcapngEPB:
+			return rp.epb(body)
+		}
+	}
+}
+
+// Parse each inbound message and frame of the capture to rebuild the cache
+// of the recorded task. If not nil, f is called with each note before it's
+// pooled.
+func (rp *Replay) Parse(f func(note interface{}, rec Recorded)) error {
+	for {
+		rec, err := rp.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if rec.Direction == DirectionOut {
+			rec.pool()
+			continue
+		}
+		note := Parse(rec.Buffer)
+		if note != nil && f != nil {
+			f(note, rec)
+		}
+		Pool(note)
+	}
+}
+
+func (rp *Replay) epb(body []byte) (Recorded, error) {
+	if len(body) < 20 {
+		return Recorded{}, ErrPcapng
+	}
+	id := rp.order.Uint32(body)
+	if int(id) >= len(rp.ifaces) {
+		return Recorded{}, fmt.Errorf("%w: interface %d", ErrPcapng, id)
+	}
+	iface := rp.ifaces[id]
+	ts := uint64(rp.order.Uint32(body[4:]))<<32 |
+		uint64(rp.order.Uint32(body[8:]))
+	n := int(rp.order.Uint32(body[12:]))
+	if n == 0 || 20+pad4(n) > len(body) {
+		return Recorded{}, ErrPcapng
+	}
+	rec := Recorded{
+		Direction: DirectionIn,
+		Time:      iface.time(ts),
+		Frame:     iface.linkType == linkTypeEthernet,
+	}
+	rp.options(body[20+pad4(n):], func(code uint16, b []byte) {
+		if code == pcapngOptFlags && len(b) == 4 {
+			rec.Direction = Direction(rp.order.Uint32(b) & 3)
+		}
+	})
+	rec.Buffer = cloneBuffer(body[20 : 20+n])
+	return rec, nil
+}
+
+// block returns the type and body of the next block, switching byte order
+// with each section header.
+func (rp *Replay) block() (uint32, []byte, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(rp.r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	if binary.LittleEndian.Uint32(hdr[:]) == pcapngSHB {
+		var magic [4]byte
+		if _, err := io.ReadFull(rp.r, magic[:]); err != nil {
+			return 0, nil, eof(err)
+		}
+		switch uint32(pcapngMagic) {
+		case binary.LittleEndian.Uint32(magic[:]):
+			rp.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic[:]):
+			rp.order = binary.BigEndian
+		default:
+			return 0, nil, ErrPcapng
+		}
+		rp.ifaces = rp.ifaces[:0]
+		n := rp.order.Uint32(hdr[4:])
+		if n < 16 || n > pcapngMaxBlock {
+			return 0, nil, ErrPcapng
+		}
+		body := make([]byte, n-12)
+		if _, err := io.ReadFull(rp.r, body); err != nil {
+			return 0, nil, eof(err)
+		}
+		return pcapngSHB, body[:len(body)-4], nil
+	}
+	if rp.order == nil {
+		return 0, nil, ErrPcapng
+	}
+	n := rp.order.Uint32(hdr[4:])
+	if n < 12 || n%4 != 0 || n > pcapngMaxBlock {
+		return 0, nil, ErrPcapng
+	}
+	body := make([]byte, n-8)
+	if _, err := io.ReadFull(rp.r, body); err != nil {
+		return 0, nil, eof(err)
+	}
+	return rp.order.Uint32(hdr[:]), body[:len(body)-4], nil
+}
+
+func (rp *Replay) options(b []byte, f func(code uint16, b []byte)) {
+	for len(b) >= 4 {
+		code := rp.order.Uint16(b)
+		n := int(rp.order.Uint16(b[2:]))
+		if code == pcapngOptEnd || 4+n > len(b) {
+			return
+		}
+		f(code, b[4:4+n])
+		if 4+pad4(n) > len(b) {
+			return
+		}
+		b = b[4+pad4(n):]
+	}
+}
+
+func (iface replayIface) time(ts uint64) time.Time {
+	r := int(iface.tsresol & 0x7f)
+	if iface.tsresol&0x80 != 0 {
+		ns := float64(ts) * 1e9 / math.Pow(2, float64(r))
+		return time.Unix(0, int64(ns))
+	}
+	for ; r < 9; r++ {
+		ts *= 10
+	}
+	for ; r > 9; r-- {
+		ts /= 10
+	}
+	return time.Unix(0, int64(ts))
+}
+
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// a truncated block is unexpected
+func eof(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
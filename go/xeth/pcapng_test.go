@@ -0,0 +1,132 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/xeth/v3/go/endian"
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/xethtest"
+)
+
+type closer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closer) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestPcapng(t *testing.T) {
+	w := new(closer)
+	rec, err := xeth.NewRecorder(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := xethtest.MsgBreak()
+	frame := append(append([]byte{}, testHa...), testHa...)
+	frame = append(frame, 0x88, 0xb5, 1, 2, 3)
+	begin := time.Now()
+	if err = rec.Msg(msg, xeth.DirectionIn); err != nil {
+		t.Fatal(err)
+	}
+	if err = rec.Frame(frame, xeth.DirectionOut); err != nil {
+		t.Fatal(err)
+	}
+	end := time.Now()
+	if err = rec.Close(); err != nil || !w.closed {
+		t.Fatal("close", err)
+	}
+	if err = rec.Msg(msg, xeth.DirectionIn); err == nil {
+		t.Error("recorded after close")
+	}
+
+	b := w.Bytes()
+	u16 := func(i int) uint16 { return endian.Host.Uint16(b[i:]) }
+	u32 := func(i int) uint32 { return endian.Host.Uint32(b[i:]) }
+	type block struct{ typ, off, n int }
+	var blocks []block
+	for i := 0; i+8 <= len(b); {
+		n := int(u32(i + 4))
+		if n < 12 || i+n > len(b) || int(u32(i+n-4)) != n {
+			t.Fatal("block", i, n)
+		}
+		blocks = append(blocks, block{int(u32(i)), i, n})
+		i += n
+	}
+	if len(blocks) != 5 {
+		t.Fatal("blocks", len(blocks))
+	}
+	shb := blocks[0].off
+	if blocks[0].typ != 0x0a0d0d0a || u32(shb+8) != 0x1a2b3c4d ||
+		u16(shb+12) != 1 || u16(shb+14) != 0 {
+		t.Error("shb", b[shb:shb+16])
+	}
+	for i, linkType := range []uint16{xeth.LinkTypeXeth, 1} {
+		idb := blocks[1+i].off
+		if blocks[1+i].typ != 1 || u16(idb+8) != linkType {
+			t.Error("idb", i, b[idb:idb+12])
+		}
+		// if_name then if_tsresol of nanoseconds
+		name := []string{"xeth", "exception"}[i]
+		opt := idb + 16
+		if u16(opt) != 2 || string(b[opt+4:opt+4+len(name)]) != name {
+			t.Error("if_name", i)
+		}
+		opt += 4 + (len(name)+3)&^3
+		if u16(opt) != 9 || u16(opt+2) != 1 || b[opt+4] != 9 {
+			t.Error("if_tsresol", i)
+		}
+	}
+	for i, packet := range [][]byte{msg, frame} {
+		epb := blocks[3+i].off
+		ts := int64(u32(epb+12))<<32 | int64(u32(epb+16))
+		if blocks[3+i].typ != 6 || u32(epb+8) != uint32(i) ||
+			ts < begin.UnixNano() || ts > end.UnixNano() ||
+			u32(epb+20) != uint32(len(packet)) ||
+			u32(epb+24) != uint32(len(packet)) ||
+			!bytes.Equal(b[epb+28:epb+28+len(packet)], packet) {
+			t.Error("epb", i)
+		}
+		// epb_flags
+		opt := epb + 28 + (len(packet)+3)&^3
+		if u16(opt) != 2 || u16(opt+2) != 4 || u32(opt+4) != uint32(i+1) {
+			t.Error("epb_flags", i)
+		}
+	}
+
+	rp := xeth.NewReplay(bytes.NewReader(b))
+	for i, expect := range []xeth.Recorded{
+		{Direction: xeth.DirectionIn},
+		{Direction: xeth.DirectionOut, Frame: true},
+	} {
+		got, err := rp.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Direction != expect.Direction || got.Frame != expect.Frame ||
+			got.Time.Before(begin) || got.Time.After(end) {
+			t.Error("replay", i, got)
+		}
+		if got.Frame {
+			f := xeth.Parse(got.Buffer).(xeth.Frame)
+			if !bytes.Equal(f.Src(), testHa) {
+				t.Error("frame", f.Src())
+			}
+			xeth.Pool(f)
+		} else if _, ok := xeth.Parse(got.Buffer).(xeth.Break); !ok {
+			t.Error("msg")
+		}
+	}
+	if _, err = rp.Next(); err != io.EOF {
+		t.Error("eof", err)
+	}
+}
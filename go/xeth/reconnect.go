@@ -0,0 +1,148 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// Disconnected notes that the driver closed the side-band socket, e.g. the
+// mux was admin-downed. The task redials the mux then notes Resynced after
+// the following dumps break.
+type Disconnected struct{}
+
+// Resynced notes that the task has reconnected and that the dumps
+// requested before the disconnect have been reissued and completed.
+type Resynced struct{}
+
+const (
+	minRedial = 100 * time.Millisecond
+	maxRedial = 3200 * time.Millisecond
+)
+
+// Reconnect the side-band socket if the driver closes it rather than
+// stopping the task. The task redials with exponential backoff from min
+// to max, then reissues DumpIfInfo and, if previously requested, DumpFib.
+// Zero durations select the defaults of 100ms and 3.2s.
+func Reconnect(min, max time.Duration) TaskOption {
+	return func(task *Task) {
+		if min <= 0 {
+			min = minRedial
+		}
+		if max < min {
+			max = maxRedial
+			if max < min {
+				max = min
+			}
+		}
+		task.redial.min = min
+		task.redial.max = max
+	}
+}
+
+// dial the mux with backoff from min to max until connected, the context is
+// done, or a non-transient error
+func dial(ctx context.Context, a *net.UnixAddr,
+	min, max time.Duration) (*net.UnixConn, error) {
+	backoff := min
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+	for {
+		s, err := net.DialUnix(unixpacket, nil, a)
+		if err == nil {
+			return s, nil
+		} else if !isEAGAIN(err) &&
+			!errors.Is(err, syscall.ECONNREFUSED) &&
+			!errors.Is(err, syscall.ENOENT) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		if backoff < max {
+			backoff *= 2
+			if backoff > max {
+				backoff = max
+			}
+		}
+		t.Reset(backoff)
+	}
+}
+
+// reconnect after a disconnect and return the number of reissued dumps; if
+// not in reconnect mode or the error isn't a disconnect, fail the task.
+// Either way, redial with the previous protocol version if the driver
+// didn't speak the current one.
+func (task *Task) reconnect(err error, heard bool) (int, error) {
+	fallback := task.fallback(err, heard)
+	if !fallback && (task.redial.max == 0 || !isDisconnect(err)) {
+		return 0, err
+	}
+	task.resetMarks()
+	if task.redial.max > 0 && !task.note(internal.MsgKindDisconnected) {
+		return 0, task.ctx.Err()
+	}
+	task.mutex.Lock()
+	sock := task.sock
+	task.mutex.Unlock()
+	sock.Close()
+	min, max := task.redial.min, task.redial.max
+	if max == 0 {
+		min, max = minRedial, minRedial
+	}
+	sock, err = dial(task.ctx, task.atsockaddr, min, max)
+	if err != nil {
+		return 0, err
+	}
+	if fallback {
+		atomic.AddUint32(&task.version, ^uint32(0))
+	} else if !task.pinned {
+		// the driver may have been upgraded
+		atomic.StoreUint32(&task.version, MaxProtocolVersion)
+	}
+	atomic.StoreUint32(&task.spoke, 0)
+	task.mutex.Lock()
+	task.sock = sock
+	dumpfib := task.dumpfib
+	task.mutex.Unlock()
+	task.stats.reset()
+	// flush the stats requeued by the disconnect
+	task.stats.signal()
+	task.statNames.reset()
+	task.metrics.Reconnects.Inc()
+	if err = task.DumpIfInfo(task.ctx); err != nil {
+		return 0, err
+	}
+	n := 1
+	if dumpfib {
+		if err = task.DumpFib(task.ctx); err != nil {
+			return 1, err
+		}
+		n++
+	}
+	if task.redial.max == 0 {
+		// only note Resynced in reconnect mode
+		n = 0
+	}
+	return n, nil
+}
+
+func isDisconnect(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ENOTCONN)
+}
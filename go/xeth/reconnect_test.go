@@ -0,0 +1,43 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/xethtest"
+)
+
+func TestReconnect(t *testing.T) {
+	mux, task := startTask(t,
+		xeth.Reconnect(10*time.Millisecond, 50*time.Millisecond))
+	mux.IfInfo(xethtest.MsgIfInfo(31, "xeth31", 31, xeth.DevKindPort,
+		xethtest.ReasonDump, testHa))
+	if err := task.DumpIfInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	untilBreak(t, task)
+	mux.Disconnect()
+	if note := next(t, task); note != (xeth.Disconnected{}) {
+		t.Fatal("disconnected", note)
+	}
+	notes := untilBreak(t, task)
+	if len(notes) != 3 || notes[0] != xeth.MarkIfInfo ||
+		notes[1] != xeth.DevDump(31) {
+		t.Fatal("redump", notes)
+	}
+	if note := next(t, task); note != (xeth.Resynced{}) {
+		t.Fatal("resynced", note)
+	}
+	if n := task.Metrics().Reconnects.Count(); n != 1 {
+		t.Error("reconnects", n)
+	}
+	if err := task.Err(); err != nil {
+		t.Error(err)
+	}
+}
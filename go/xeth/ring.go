@@ -0,0 +1,332 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/platinasystems/xeth/v3/go/endian"
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// The exception frame ring is a PACKET_MMAP, TPACKET_V3 receive ring of
+// blocks that the kernel retires to user space when full or after a short
+// timeout. The rx routine polls the raw socket along with a wake pipe that
+// is closed to promptly stop the task. Each frame is still copied from its
+// block to a pooled buffer so that the block may be returned to the kernel.
+type ring struct {
+	mem       []byte
+	blockSize int
+	blocks    int
+	block     int
+	// wake[0] is polled with the raw socket; closing wake[1] stops rx
+	wake [2]int
+}
+
+const (
+	defaultRingBlockSize = 1 << 17
+	defaultRingBlocks    = 16
+	ringFrameSize        = 1 << 11
+	ringRetireMsec       = 10
+)
+
+// from linux/if_packet.h
+const (
+	packetRxRing    = 5
+	packetVersion   = 10
+	packetStatistic = 6
+	tpacketV3       = 2
+
+	tpStatusKernel        = 0
+	tpStatusUser          = 1 << 0
+	tpStatusVlanValid     = 1 << 4
+	tpStatusVlanTpidValid = 1 << 6
+
+	// tpacket_block_desc
+	bdBlockStatus      = 8
+	bdNumPkts          = 12
+	bdOffsetToFirstPkt = 16
+
+	// tpacket3_hdr
+	tp3NextOffset = 0
+	tp3Snaplen    = 12
+	tp3Status     = 20
+	tp3Mac        = 24
+	tp3VlanTci    = 32
+	tp3VlanTpid   = 36
+	sizeofTp3Hdr  = 48
+
+	// sockaddr_ll that follows the tpacket3_hdr
+	sllIfindex = 4
+)
+
+type tpacketReq3 struct {
+	BlockSize      uint32
+	BlockNr        uint32
+	FrameSize      uint32
+	FrameNr        uint32
+	RetireBlkTov   uint32
+	SizeofPriv     uint32
+	FeatureReqWord uint32
+}
+
+type tpacketStatsV3 struct {
+	Packets    uint32
+	Drops      uint32
+	FreezeQCnt uint32
+}
+
+type pollFd struct {
+	Fd      int32
+	Events  int16
+	Revents int16
+}
+
+const (
+	pollIn  = 0x1
+	pollErr = 0x8
+)
+
+// RawFilter attaches a classic BPF program to the raw socket so that only
+// the exception frames that it accepts are delivered to RxCh.
+func RawFilter(prog []syscall.SockFilter) TaskOption {
+	return func(task *Task) {
+		task.rawfilter = prog
+	}
+}
+
+// RawRing sizes the exception frame ring, default 16 blocks of 128KiB.
+// The block size is rounded up to a multiple of the page size.
+// Zero blocks receive each frame with a recvfrom syscall instead.
+func RawRing(blockSize, blocks int) TaskOption {
+	return func(task *Task) {
+		if blockSize < ringFrameSize {
+			blockSize = ringFrameSize
+		}
+		blockSize = (blockSize + PageSize - 1) &^ (PageSize - 1)
+		task.rawring.blockSize = blockSize
+		task.rawring.blocks = blocks
+	}
+}
+
+// Configure the raw socket with optional filter and ring; if the kernel
+// doesn't support the ring, the task reverts to recvfrom with a receive
+// timeout to notice the done context. Other ring errors fail the setup.
+func (task *Task) setupRaw() error {
+	if len(task.rawfilter) > 0 {
+		err := syscall.AttachLsf(task.muxfd, task.rawfilter)
+		if err != nil {
+			return err
+		}
+	}
+	if task.rawring.blocks > 0 {
+		r, err := newRing(task.muxfd, task.rawring.blockSize,
+			task.rawring.blocks)
+		if err == nil {
+			task.ring = r
+			return nil
+		}
+		if err != errRingUnsupported {
+			return err
+		}
+	}
+	rawrxto := syscall.NsecToTimeval(int64(100 * time.Millisecond))
+	return syscall.SetsockoptTimeval(task.muxfd, syscall.SOL_SOCKET,
+		syscall.SO_RCVTIMEO, &rawrxto)
+}
+
+// errRingUnsupported is returned by newRing if the kernel refuses a
+// TPACKET_V3 receive ring; the socket is unchanged.
+var errRingUnsupported = errors.New("TPACKET_V3 rx ring unsupported")
+
+func newRing(fd, blockSize, blocks int) (*ring, error) {
+	r := &ring{
+		blockSize: blockSize,
+		blocks:    blocks,
+	}
+	err := syscall.SetsockoptInt(fd, syscall.SOL_PACKET, packetVersion,
+		tpacketV3)
+	if err != nil {
+		return nil, errRingUnsupported
+	}
+	req := tpacketReq3{
+		BlockSize:    uint32(blockSize),
+		BlockNr:      uint32(blocks),
+		FrameSize:    ringFrameSize,
+		FrameNr:      uint32(blocks * blockSize / ringFrameSize),
+		RetireBlkTov: ringRetireMsec,
+	}
+	if e := setRxRing(fd, &req); e != 0 {
+		return nil, errRingUnsupported
+	}
+	r.mem, err = syscall.Mmap(fd, 0, blockSize*blocks,
+		syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err == nil {
+		err = syscall.Pipe2(r.wake[:], syscall.O_CLOEXEC)
+		if err == nil {
+			return r, nil
+		}
+		syscall.Munmap(r.mem)
+	}
+	// detach the ring so that recvfrom could still deliver frames
+	if e := setRxRing(fd, &tpacketReq3{}); e != 0 {
+		return nil, fmt.Errorf("%w; rx ring detach: %v", err, e)
+	}
+	return nil, err
+}
+
+func setRxRing(fd int, req *tpacketReq3) syscall.Errno {
+	_, _, e := syscall.Syscall6(sysSetsockopt, uintptr(fd),
+		syscall.SOL_PACKET, packetRxRing, uintptr(unsafe.Pointer(req)),
+		unsafe.Sizeof(*req), 0)
+	return e
+}
+
+// wake the rx routine to notice the done context
+func (r *ring) stop() {
+	syscall.Close(r.wake[1])
+}
+
+func (r *ring) close() {
+	syscall.Close(r.wake[0])
+	syscall.Munmap(r.mem)
+}
+
+func (r *ring) status(block []byte) *uint32 {
+	return (*uint32)(unsafe.Pointer(&block[bdBlockStatus]))
+}
+
+// poll the raw socket and wake pipe; returns false if woken to stop
+func (r *ring) poll(fd int) (bool, error) {
+	fds := [2]pollFd{
+		{Fd: int32(fd), Events: pollIn},
+		{Fd: int32(r.wake[0]), Events: pollIn},
+	}
+	_, _, e := syscall.Syscall6(syscall.SYS_PPOLL,
+		uintptr(unsafe.Pointer(&fds[0])), uintptr(len(fds)),
+		0, 0, 0, 0)
+	if e == syscall.EINTR {
+		return true, nil
+	} else if e != 0 {
+		return false, e
+	}
+	if fds[1].Revents != 0 {
+		return false, nil
+	}
+	if fds[0].Revents&pollErr != 0 {
+		soerr, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET,
+			syscall.SO_ERROR)
+		if err != nil {
+			return false, err
+		}
+		if soerr != 0 {
+			return true, syscall.Errno(soerr)
+		}
+	}
+	return true, nil
+}
+
+func (task *Task) goRingRx() {
+	defer task.wg.Done()
+	defer task.svcwg.Done()
+
+	r := task.ring
+	for {
+		block := r.mem[r.block*r.blockSize : (r.block+1)*r.blockSize]
+		status := r.status(block)
+		if atomic.LoadUint32(status)&tpStatusUser == 0 {
+			ok, err := r.poll(task.muxfd)
+			if !ok && err == nil {
+				return
+			} else if err == syscall.ENETDOWN && task.redial.max > 0 {
+				// admin-down mux; wait for it to come back up
+				select {
+				case <-task.ctx.Done():
+					return
+				case <-time.After(task.redial.min):
+				}
+			} else if err != nil {
+				task.fail(&task.rxerr, err)
+				return
+			}
+			continue
+		}
+		if !task.ringBlock(block) {
+			return
+		}
+		atomic.StoreUint32(status, tpStatusKernel)
+		r.block = (r.block + 1) % r.blocks
+		task.ringDrops()
+	}
+}
+
+// send the block's frames to rx channel; returns false if done
+func (task *Task) ringBlock(block []byte) bool {
+	n := endian.Host.Uint32(block[bdNumPkts:])
+	off := endian.Host.Uint32(block[bdOffsetToFirstPkt:])
+	for i := uint32(0); i < n; i++ {
+		hdr := block[off:]
+		snaplen := int(endian.Host.Uint32(hdr[tp3Snaplen:]))
+		mac := int(endian.Host.Uint16(hdr[tp3Mac:]))
+		sll := hdr[sizeofTp3Hdr:]
+		ifindex := int32(endian.Host.Uint32(sll[sllIfindex:]))
+		if ifindex == int32(task.muxsa.Ifindex) {
+			// truncate like recvfrom to a jumbo frame with room for
+			// a restored vlan tag
+			if snaplen > internal.SizeofJumboFrame-5 {
+				snaplen = internal.SizeofJumboFrame - 5
+			}
+			task.metrics.rx(MsgKindFrame)
+			buf := task.ringFrame(hdr, hdr[mac:mac+snaplen])
+			if task.rec != nil {
+				task.rec.Frame(buf.bytes(), DirectionIn)
+			}
+			if !task.send(buf) {
+				return false
+			}
+			Cloned.Inc()
+		}
+		off += endian.Host.Uint32(hdr[tp3NextOffset:])
+	}
+	return true
+}
+
+// clone the frame, restoring any vlan tag that was stripped by the kernel
+func (task *Task) ringFrame(hdr, b []byte) buffer {
+	status := endian.Host.Uint32(hdr[tp3Status:])
+	if status&tpStatusVlanValid == 0 || len(b) < ETH_VLAN_TCI {
+		return cloneBuffer(b)
+	}
+	tpid := uint16(ETH_P_8021Q)
+	if status&tpStatusVlanTpidValid != 0 {
+		tpid = endian.Host.Uint16(hdr[tp3VlanTpid:])
+	}
+	tci := uint16(endian.Host.Uint32(hdr[tp3VlanTci:]))
+	buf := newBuffer(len(b) + 4)
+	f := buf.bytes()
+	copy(f, b[:ETH_P])
+	endian.Network.PutUint16(f[ETH_P:], tpid)
+	endian.Network.PutUint16(f[ETH_VLAN_TCI:], tci)
+	copy(f[ETH_VLAN_P:], b[ETH_P:])
+	return buf
+}
+
+// count the frames dropped by a full ring; the kernel resets its count
+// with each read
+func (task *Task) ringDrops() {
+	var stats tpacketStatsV3
+	n := uint32(unsafe.Sizeof(stats))
+	_, _, e := syscall.Syscall6(sysGetsockopt,
+		uintptr(task.muxfd), syscall.SOL_PACKET, packetStatistic,
+		uintptr(unsafe.Pointer(&stats)), uintptr(unsafe.Pointer(&n)), 0)
+	if e == 0 && stats.Drops > 0 {
+		task.metrics.RingDropped.Add(uint64(stats.Drops))
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+)
+
+func TestRawRx(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []xeth.TaskOption
+	}{
+		{"ring", nil},
+		{"recvfrom", []xeth.TaskOption{xeth.RawRing(0, 0)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mux, task := startTask(t, tc.opts...)
+			if err := mux.Attach(testPeer); err != nil {
+				t.Fatal(err)
+			}
+			frame := make([]byte, 64)
+			copy(frame, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+			copy(frame[6:], testHa)
+			frame[12], frame[13] = 0x88, 0xb5 // local experimental
+			if err := mux.Inject(frame); err != nil {
+				t.Fatal(err)
+			}
+			for {
+				f, ok := next(t, task).(xeth.Frame)
+				if ok && bytes.Equal(f.Src(), testHa) {
+					xeth.Pool(f)
+					break
+				}
+			}
+		})
+	}
+}
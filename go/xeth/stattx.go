@@ -0,0 +1,319 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// Pending stats are coalesced by kind, xid, and index so that only the
+// latest count is sent. The tx routine flushes them in sendmmsg batches.
+type stattx struct {
+	mutex   sync.Mutex
+	pending map[statKey]uint64
+	keys    []statKey // in order of first pending
+	wake    chan struct{}
+	links   map[Xid]*LinkStats // last of SetLinkStats
+
+	// the following are only used by the tx routine
+	flushing []statKey
+	mirror   mirror
+	msgs     [statBatch]internal.MsgStat
+	iovs     [statBatch]syscall.Iovec
+	hdrs     [statBatch]mmsghdr
+}
+
+type statKey struct {
+	kind  uint8
+	xid   Xid
+	index uint32
+}
+
+type mmsghdr struct {
+	Hdr syscall.Msghdr
+	Len uint32
+}
+
+// Stats sent by a flush are copied then stored in each Link so that
+// readers of Link.LinkStats and Link.Stats see consistent snapshots.
+type mirror map[Xid]*mirrored
+
+type mirrored struct {
+	l       *Link
+	link    *LinkStats
+	ethtool []uint64
+}
+
+const statBatch = 64
+
+// the driver's xeth_mux_max_stats
+const maxEthtoolStats = 512
+
+const statTimeout = 10 * time.Millisecond
+
+// Send latest link stats to driver in batches with the other pending stats.
+func (task *Task) SetLinkStatMap(ctx context.Context, xid Xid,
+	stats map[LinkStat]uint64) error {
+	if err := task.stopped(ctx); err != nil {
+		return err
+	}
+	task.stats.mutex.Lock()
+	for stat, n := range stats {
+		task.stats.set(internal.MsgKindLinkStat, xid, uint32(stat), n)
+	}
+	task.stats.mutex.Unlock()
+	task.stats.signal()
+	return nil
+}
+
+// Send latest ethtool stats to driver in batches with the other pending
+// stats.
+func (task *Task) SetEthtoolStatMap(ctx context.Context, xid Xid,
+	stats map[uint32]uint64) error {
+	if err := task.stopped(ctx); err != nil {
+		return err
+	}
+	task.stats.mutex.Lock()
+	for stat, n := range stats {
+		task.stats.set(internal.MsgKindEthtoolStat, xid, stat, n)
+	}
+	task.stats.mutex.Unlock()
+	task.stats.signal()
+	return nil
+}
+
+// Stats are pending until the tx routine's next flush, so never wait; the
+// latest count of each supersedes any still pending.
+func (task *Task) setStat(ctx context.Context, kind uint8, xid Xid,
+	stat uint32, n uint64) error {
+	if err := task.stopped(ctx); err != nil {
+		return err
+	}
+	task.stats.mutex.Lock()
+	task.stats.set(kind, xid, stat, n)
+	task.stats.mutex.Unlock()
+	task.stats.signal()
+	return nil
+}
+
+func (s *stattx) init() {
+	s.pending = make(map[statKey]uint64)
+	s.links = make(map[Xid]*LinkStats)
+	s.mirror = make(mirror)
+	s.wake = make(chan struct{}, 1)
+	for i := range s.hdrs {
+		s.iovs[i].Base = (*byte)(unsafe.Pointer(&s.msgs[i]))
+		s.iovs[i].SetLen(internal.SizeofMsgStat)
+		s.hdrs[i].Hdr.Iov = &s.iovs[i]
+		s.hdrs[i].Hdr.Iovlen = 1
+	}
+}
+
+// set pending stat with mutex held
+func (s *stattx) set(kind uint8, xid Xid, index uint32, n uint64) {
+	k := statKey{kind, xid, index}
+	if _, found := s.pending[k]; !found {
+		s.keys = append(s.keys, k)
+	} else {
+		Coalesced.Inc()
+	}
+	s.pending[k] = n
+}
+
+// wake the tx routine
+func (s *stattx) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Send the pending stats. Those not sent before timeout or a reconnect are
+// requeued unless superseded.
+func (task *Task) flushStats() error {
+	s := &task.stats
+	s.mutex.Lock()
+	keys := s.keys
+	counts := s.pending
+	s.keys = s.flushing[:0]
+	s.pending = make(map[statKey]uint64, len(counts))
+	s.mutex.Unlock()
+	defer func() { s.flushing = keys }()
+
+	task.mutex.Lock()
+	sock := task.sock
+	task.mutex.Unlock()
+	if err := sock.SetWriteDeadline(time.Now().Add(statTimeout)); err != nil {
+		return task.unflushed(keys, counts, sock, err)
+	}
+	rc, err := sock.SyscallConn()
+	if err != nil {
+		return task.unflushed(keys, counts, sock, err)
+	}
+	defer s.mirror.store()
+	version := task.ProtocolVersion()
+	for i := 0; i < len(keys); {
+		n := len(keys) - i
+		if n > statBatch {
+			n = statBatch
+		}
+		for j, k := range keys[i : i+n] {
+			msg := &s.msgs[j]
+			msg.Header.Set(k.kind)
+			msg.Header.Version = version
+			msg.Xid = uint32(k.xid)
+			msg.Index = k.index
+			msg.Count = counts[k]
+		}
+		sent, err := s.sendmmsg(rc, n)
+		if sent > 0 {
+			atomic.StoreUint32(&task.spoke, 1)
+		}
+		for j := 0; j < sent; j++ {
+			Sent.Inc()
+			task.metrics.tx(MsgKind(s.msgs[j].Header.Kind))
+			s.mirror.sent(&s.msgs[j])
+			if task.rec != nil {
+				b := (*[internal.SizeofMsgStat]byte)(
+					unsafe.Pointer(&s.msgs[j]))
+				task.rec.Msg(b[:], DirectionOut)
+			}
+		}
+		i += sent
+		if err != nil {
+			return task.unflushed(keys[i:], counts, sock, err)
+		}
+	}
+	return nil
+}
+
+// Requeue the unsent stats of a flush that timed out or was disconnected
+// in reconnect mode; otherwise drop them and return the error that stops
+// the task.
+func (task *Task) unflushed(keys []statKey, counts map[statKey]uint64,
+	sock *net.UnixConn, err error) error {
+	s := &task.stats
+	switch {
+	case isTimeout(err):
+		s.requeue(keys, counts, true)
+	case task.redial.max > 0 && isDisconnect(err):
+		// resend with the first flush after reconnect
+		task.mutex.Lock()
+		redialed := task.sock != sock
+		task.mutex.Unlock()
+		s.requeue(keys, counts, redialed)
+	default:
+		Dropped.Add(uint64(len(keys)))
+		return err
+	}
+	return nil
+}
+
+func (m mirror) sent(msg *internal.MsgStat) {
+	xid := Xid(msg.Xid)
+	v := m[xid]
+	if v == nil {
+		l := LinkOf(xid)
+		if l == nil {
+			return
+		}
+		v = &mirrored{l: l}
+		m[xid] = v
+	}
+	switch msg.Header.Kind {
+	case internal.MsgKindLinkStat:
+		if msg.Index >= NLinkStat {
+			return
+		}
+		if v.link == nil {
+			stats := v.l.LinkStats()
+			v.link = &stats
+		}
+		v.link.Array()[msg.Index] = msg.Count
+	case internal.MsgKindEthtoolStat:
+		if msg.Index >= maxEthtoolStats {
+			return
+		}
+		if v.ethtool == nil {
+			prev := v.l.Stats()
+			n := len(v.l.StatNames())
+			if n < len(prev) {
+				n = len(prev)
+			}
+			v.ethtool = make([]uint64, n)
+			copy(v.ethtool, prev)
+		}
+		for int(msg.Index) >= len(v.ethtool) {
+			v.ethtool = append(v.ethtool, 0)
+		}
+		v.ethtool[msg.Index] = msg.Count
+	}
+}
+
+func (m mirror) store() {
+	for xid, v := range m {
+		if v.link != nil {
+			v.l.LinkStats(*v.link)
+		}
+		if v.ethtool != nil {
+			v.l.Stats(v.ethtool)
+		}
+		delete(m, xid)
+	}
+}
+
+func (s *stattx) sendmmsg(rc syscall.RawConn, n int) (int, error) {
+	var sent int
+	var errno syscall.Errno
+	err := rc.Write(func(fd uintptr) bool {
+		r, _, e := syscall.Syscall6(sysSendmmsg, fd,
+			uintptr(unsafe.Pointer(&s.hdrs[0])), uintptr(n),
+			0, 0, 0)
+		if e == syscall.EAGAIN {
+			return false
+		}
+		sent, errno = int(r), e
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if errno != 0 {
+		return 0, errno
+	}
+	return sent, nil
+}
+
+// forget the last link stats so that the next SetLinkStats sends all
+func (s *stattx) reset() {
+	s.mutex.Lock()
+	s.links = make(map[Xid]*LinkStats)
+	s.mutex.Unlock()
+}
+
+// retry unsent stats that weren't updated during the flush; with wake, the
+// tx routine flushes again without waiting for another stat
+func (s *stattx) requeue(keys []statKey, counts map[statKey]uint64,
+	wake bool) {
+	s.mutex.Lock()
+	for _, k := range keys {
+		if _, found := s.pending[k]; !found {
+			s.keys = append(s.keys, k)
+			s.pending[k] = counts[k]
+		}
+	}
+	s.mutex.Unlock()
+	if wake {
+		s.signal()
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+	"github.com/platinasystems/xeth/v3/go/xeth/xethtest"
+)
+
+func TestStatsAfterReconnect(t *testing.T) {
+	mux, task := startTask(t,
+		xeth.Reconnect(10*time.Millisecond, 50*time.Millisecond))
+	go func() {
+		for buf := range task.RxCh {
+			xeth.Pool(xeth.Parse(buf))
+		}
+	}()
+	ctx := context.Background()
+	dropped := xeth.Dropped.Count()
+	const xid, stat = 81, xeth.LinkStatRxPackets
+	if err := task.SetLinkStat(ctx, xid, stat, 7); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		n, _ := mux.LinkStat(xid, stat)
+		return n == 7
+	})
+	mux.Disconnect()
+	// the stats of a flush that fails with the disconnect are resent
+	for i := uint64(8); i < 100; i++ {
+		if err := task.SetLinkStat(ctx, xid, stat, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	eventually(t, func() bool {
+		n, _ := mux.LinkStat(xid, stat)
+		return n == 99
+	})
+	if n := xeth.Dropped.Count() - dropped; n != 0 {
+		t.Error("dropped", n)
+	}
+}
+
+func TestSetLinkStats(t *testing.T) {
+	mux, task := startTask(t)
+	ctx := context.Background()
+	const xid = 82
+	// the count of link stat messages sent for xid
+	sent := func() (n int) {
+		for _, b := range mux.Received() {
+			msg := (*internal.MsgStat)(unsafe.Pointer(&b[0]))
+			if msg.Header.Kind == internal.MsgKindLinkStat &&
+				msg.Xid == xid {
+				n++
+			}
+		}
+		return
+	}
+	var stats xeth.LinkStats
+	for i := range stats.Array() {
+		stats.Array()[i] = 1
+	}
+	if err := task.SetLinkStats(ctx, xid, &stats); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool { return sent() == xeth.NLinkStat })
+	stats.RxPackets = 2
+	stats.TxBytes = 3
+	if err := task.SetLinkStats(ctx, xid, &stats); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		n, _ := mux.LinkStat(xid, xeth.LinkStatTxBytes)
+		return n == 3
+	})
+	eventually(t, func() bool {
+		n, _ := mux.LinkStat(xid, xeth.LinkStatRxPackets)
+		return n == 2
+	})
+	if err := task.SetLinkStats(ctx, xid, &stats); err != nil {
+		t.Fatal(err)
+	}
+	// a later stat flushes after any unchanged stats would have
+	if err := task.SetLinkStat(ctx, xid+1, xeth.LinkStatRxPackets,
+		1); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		_, found := mux.LinkStat(xid+1, xeth.LinkStatRxPackets)
+		return found
+	})
+	if n := sent(); n != xeth.NLinkStat+2 {
+		t.Error("sent", n)
+	}
+	for stat, want := range map[xeth.LinkStat]uint64{
+		xeth.LinkStatRxPackets: 2,
+		xeth.LinkStatTxBytes:   3,
+		xeth.LinkStatTxPackets: 1,
+	} {
+		if n, _ := mux.LinkStat(xid, stat); n != want {
+			t.Error(stat, n)
+		}
+	}
+}
+
+func TestStatsMirror(t *testing.T) {
+	mux, task := startTask(t)
+	ctx := context.Background()
+	const xid = 91
+	mux.IfInfo(xethtest.MsgIfInfo(xid, "xeth91", 91, xeth.DevKindPort,
+		xethtest.ReasonDump, testHa))
+	if err := task.DumpIfInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	untilBreak(t, task)
+	var stats xeth.LinkStats
+	for i := range stats.Array() {
+		stats.Array()[i] = uint64(100 + i)
+	}
+	if err := task.SetLinkStats(ctx, xid, &stats); err != nil {
+		t.Fatal(err)
+	}
+	stats.TxBytes = 1
+	if err := task.SetLinkStat(ctx, xid, xeth.LinkStatTxBytes,
+		stats.TxBytes); err != nil {
+		t.Fatal(err)
+	}
+	ethtool := []uint64{2, 0, 0, 5}
+	for _, i := range []uint32{0, 3} {
+		err := task.SetEthtoolStat(ctx, xid, i, ethtool[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the mux has what was sent
+	eventually(t, func() bool {
+		var sent xeth.LinkStats
+		for i := range sent.Array() {
+			sent.Array()[i], _ = mux.LinkStat(xid, xeth.LinkStat(i))
+		}
+		first, _ := mux.EthtoolStat(xid, 0)
+		last, _ := mux.EthtoolStat(xid, 3)
+		return sent == stats && first == 2 && last == 5
+	})
+	// and the link mirrors it after the flush
+	l := xeth.LinkOf(xid)
+	eventually(t, func() bool {
+		return l.LinkStats() == stats &&
+			reflect.DeepEqual(l.Stats(), ethtool)
+	})
+}
+
+// wait for the condition
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
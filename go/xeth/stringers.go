@@ -16,7 +16,93 @@ func (xid Xid) Format(w fmt.State, c rune) {
 	}
 }
 
-func (Break) String() string { return "break" }
+func (b Break) Format(w fmt.State, c rune) {
+	fmt.Fprint(w, "break")
+	if len(b.Swept) > 0 {
+		fmt.Fprint(w, " swept ", len(b.Swept))
+	}
+}
+
+func (m Mark) String() string {
+	if m == MarkFib {
+		return "mark fib"
+	}
+	return "mark ifinfo"
+}
+
+func (Disconnected) String() string { return "disconnected" }
+
+func (Resynced) String() string { return "resynced" }
+
+func (o Overrun) Format(w fmt.State, c rune) {
+	fmt.Fprint(w, "overrun")
+	for kind := 0; kind < 1<<8; kind++ {
+		if n := o.Dropped[MsgKind(kind)]; n > 0 {
+			fmt.Fprint(w, " ", MsgKind(kind), " ", n)
+		}
+	}
+}
+
+func (note CarrierDampened) Format(w fmt.State, c rune) {
+	if note.Suppressed {
+		fmt.Fprint(w, note.Xid, " carrier suppressed")
+	} else {
+		fmt.Fprint(w, note.Xid, " carrier reused")
+	}
+	fmt.Fprint(w, " after ", note.Transitions, " dampened changes")
+}
+
+var msgKindNames = map[MsgKind]string{
+	MsgKindBreak:                         "break",
+	MsgKindLinkStat:                      "link-stat",
+	MsgKindEthtoolStat:                   "ethtool-stat",
+	MsgKindEthtoolFlags:                  "ethtool-flags",
+	MsgKindEthtoolSettings:               "ethtool-settings",
+	MsgKindEthtoolLinkModesSupported:     "supported-link-modes",
+	MsgKindEthtoolLinkModesAdvertising:   "advertising-link-modes",
+	MsgKindEthtoolLinkModesLPAdvertising: "lp-advertising-link-modes",
+	MsgKindDumpIfInfo:                    "dump-ifinfo",
+	MsgKindCarrier:                       "carrier",
+	MsgKindSpeed:                         "speed",
+	MsgKindIfInfo:                        "ifinfo",
+	MsgKindIfa:                           "ifa",
+	MsgKindIfa6:                          "ifa6",
+	MsgKindDumpFibInfo:                   "dump-fibinfo",
+	MsgKindFibEntry:                      "fib-entry",
+	MsgKindFib6Entry:                     "fib6-entry",
+	MsgKindNeighUpdate:                   "neighbor-update",
+	MsgKindChangeUpperXid:                "change-upper",
+	MsgKindNetNsAdd:                      "netns-add",
+	MsgKindNetNsDel:                      "netns-del",
+	MsgKindEthtoolPause:                  "ethtool-pause",
+	MsgKindEthtoolFec:                    "ethtool-fec",
+	MsgKindEthtoolEee:                    "ethtool-eee",
+	MsgKindDisconnected:                  "disconnected",
+	MsgKindResynced:                      "resynced",
+	MsgKindMarkIfInfo:                    "mark-ifinfo",
+	MsgKindMarkFib:                       "mark-fib",
+	MsgKindOverrun:                       "overrun",
+	MsgKindFrame:                         "frame",
+	MsgKindCarrierDampened:               "carrier-dampened",
+}
+
+func (kind MsgKind) String() string {
+	s, found := msgKindNames[kind]
+	if !found {
+		s = fmt.Sprint("unknown-", uint8(kind))
+	}
+	return s
+}
+
+func (dir Direction) String() string {
+	switch dir {
+	case DirectionIn:
+		return "in"
+	case DirectionOut:
+		return "out"
+	}
+	return "unknown"
+}
 
 func (dev DevNew) Format(w fmt.State, c rune) {
 	xid := Xid(dev)
@@ -84,16 +170,120 @@ func (dev *DevEthtoolFlags) Format(w fmt.State, c rune) {
 }
 
 func (dev DevEthtoolSettings) Format(w fmt.State, c rune) {
-	xid := Xid(dev)
-	fmt.Fprint(w, xid)
-	if l := LinkOf(xid); l != nil {
-		fmt.Fprint(w, " speed ", l.EthtoolSpeed(), " (mbps)")
-		fmt.Fprint(w, " autoneg ", l.EthtoolAutoNeg())
-		fmt.Fprint(w, " duplex ", l.EthtoolDuplex())
-		fmt.Fprint(w, " port ", l.EthtoolDevPort())
-	} else {
-		fmt.Fprint(w, " ethtool settings")
+	fmt.Fprint(w, dev.Xid)
+	fmt.Fprint(w, " speed ", dev.Speed, " (mbps)")
+	fmt.Fprint(w, " autoneg ", dev.AutoNeg)
+	fmt.Fprint(w, " duplex ", dev.Duplex)
+	fmt.Fprint(w, " port ", dev.Port)
+	fmt.Fprint(w, " phy ", dev.PhyAddress)
+	fmt.Fprint(w, " mdio <", dev.MdioSupport, ">")
+	fmt.Fprint(w, " mdix ", dev.EthTpMdix)
+	fmt.Fprint(w, " mdix-ctrl ", dev.EthTpMdixCtrl)
+	fmt.Fprint(w, " changed <", dev.Changed, ">")
+}
+
+func (dev *DevEthtoolPause) Format(w fmt.State, c rune) {
+	fmt.Fprint(w, dev.Xid, " pause")
+	fmt.Fprint(w, " autoneg ", onOff(dev.AutoNeg))
+	fmt.Fprint(w, " rx ", onOff(dev.Rx))
+	fmt.Fprint(w, " tx ", onOff(dev.Tx))
+}
+
+func (dev *DevEthtoolFec) Format(w fmt.State, c rune) {
+	fmt.Fprint(w, dev.Xid, " fec <", dev.Configured, ">")
+	fmt.Fprint(w, " active <", dev.Active, ">")
+}
+
+func (dev *DevEthtoolEee) Format(w fmt.State, c rune) {
+	fmt.Fprint(w, dev.Xid, " eee ", onOff(dev.Enabled))
+	fmt.Fprint(w, " active ", onOff(dev.Active))
+	fmt.Fprint(w, " tx-lpi ", onOff(dev.TxLpiEnabled))
+	fmt.Fprint(w, " tx-lpi-timer ", dev.TxLpiTimer, " (usec)")
+	fmt.Fprint(w, " advertised <", dev.Advertised, ">")
+	fmt.Fprint(w, " lp-advertised <", dev.LPAdvertised, ">")
+}
+
+func (bits EthtoolFecBits) String() string {
+	if bits == 0 {
+		return "unknown"
 	}
+	s := ""
+	sep := ""
+	for i, name := range []string{
+		"none",
+		"auto",
+		"off",
+		"rs",
+		"baser",
+		"llrs",
+	} {
+		if bits.Has(1 << uint(i)) {
+			s += sep + name
+			sep = ", "
+		}
+	}
+	if rest := bits &^ (1<<6 - 1); rest != 0 {
+		s += sep + fmt.Sprintf("0x%x", uint32(rest))
+	}
+	return s
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func (fields EthtoolSettingsFields) String() string {
+	if fields == 0 {
+		return "none"
+	}
+	s := ""
+	sep := ""
+	for i, name := range []string{
+		"speed",
+		"duplex",
+		"port",
+		"phy-address",
+		"autoneg",
+		"mdio-support",
+		"mdix",
+		"mdix-ctrl",
+	} {
+		if fields.Has(1 << uint(i)) {
+			s += sep + name
+			sep = ", "
+		}
+	}
+	return s
+}
+
+func (mdix EthTpMdix) String() string {
+	s, found := map[EthTpMdix]string{
+		ETH_TP_MDI_INVALID: "invalid",
+		ETH_TP_MDI:         "mdi",
+		ETH_TP_MDI_X:       "mdi-x",
+		ETH_TP_MDI_AUTO:    "auto",
+	}[mdix]
+	if !found {
+		s = fmt.Sprint("unknown-", uint8(mdix))
+	}
+	return s
+}
+
+func (mdio MdioSupport) String() string {
+	switch mdio {
+	case 0:
+		return "none"
+	case ETH_MDIO_SUPPORTS_C22:
+		return "c22"
+	case ETH_MDIO_SUPPORTS_C45:
+		return "c45"
+	case ETH_MDIO_SUPPORTS_C22 | ETH_MDIO_SUPPORTS_C45:
+		return "c22, c45"
+	}
+	return fmt.Sprintf("0b%b", uint8(mdio))
 }
 
 func (dev DevLinkModesSupported) Format(w fmt.State, c rune) {
@@ -134,72 +324,24 @@ func (bits EthtoolFlagBits) Format(w fmt.State, c rune) {
 	}
 }
 
-func (bits EthtoolLinkModeBits) Format(w fmt.State, c rune) {
+func (modes EthtoolLinkModeBits) Format(w fmt.State, c rune) {
 	sep := ""
-	for i, s := range []string{
-		"10baseT-half",
-		"10baseT-full",
-		"100baseT-half",
-		"100baseT-full",
-		"1000baseT-half",
-		"1000baseT-full",
-		"Autoneg",
-		"TP",
-		"AUI",
-		"MII",
-		"FIBRE",
-		"BNC",
-		"10000baseT-full",
-		"Pause",
-		"Asym-Pause",
-		"2500baseX-full",
-		"Backplane",
-		"1000baseKX-full",
-		"10000baseKX4-full",
-		"10000baseKR-full",
-		"10000baseR-FEC",
-		"20000baseMLD2-full",
-		"20000baseKR2-full",
-		"40000baseKR4-full",
-		"40000baseCR4-full",
-		"40000baseSR4-full",
-		"40000baseLR4-full",
-		"56000baseKR4-full",
-		"56000baseCR4-full",
-		"56000baseSR4-full",
-		"56000baseLR4-full",
-		"25000baseCR-full",
-		"25000baseKR-full",
-		"25000baseSR-full",
-		"50000baseCR2-full",
-		"50000baseKR2-full",
-		"100000baseKR4-full",
-		"100000baseSR4-full",
-		"100000baseCR4-full",
-		"100000baseLR4-ER4-full",
-		"50000baseSR2-full",
-		"1000baseX-full",
-		"10000baseCR-full",
-		"10000baseSR-full",
-		"10000baseLR-full",
-		"10000baseLRM-full",
-		"10000baseER-full",
-		"2500baseT-full",
-		"5000baseT-full",
-		"fec-none",
-		"fec-rs",
-		"fec-baser",
-	} {
-		if bits.Test(uint(i)) {
-			fmt.Fprint(w, sep, s)
-			sep = ", "
-		}
+	for _, bit := range modes.Bits() {
+		fmt.Fprint(w, sep, EthtoolLinkMode(bit))
+		sep = ", "
 	}
 	if len(sep) == 0 {
 		fmt.Fprint(w, "none")
 	}
 }
 
+func (mode EthtoolLinkMode) String() string {
+	if int(mode) < len(ethtoolLinkModeNames) {
+		return ethtoolLinkModeNames[mode]
+	}
+	return fmt.Sprint("bit-", uint(mode))
+}
+
 func (msg *FibEntry) Format(w fmt.State, c rune) {
 	fmt.Fprint(w, msg.FibEntryEvent)
 	fmt.Fprint(w, " netns ", msg.NetNs)
@@ -459,3 +601,7 @@ func (f IfInfoFeatures) Format(w fmt.State, c rune) {
 		fmt.Fprint(w, "off")
 	}
 }
+
+func (msg UnknownMsg) String() string {
+	return fmt.Sprint(msg.Kind, " msg, length ", len(msg.Bytes))
+}
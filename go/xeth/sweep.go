@@ -0,0 +1,245 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// Mark notes the start of a dump requested by the task. Each mark
+// increments the generation of its cached entries; those not refreshed by
+// the dump are swept at its Break. The driver only replays the FIB with the
+// first DumpFib of a session, so the marks of later FIB dumps don't sweep.
+type Mark uint8
+
+const (
+	MarkIfInfo Mark = iota
+	MarkFib
+)
+
+var generation struct {
+	sync.Mutex
+	ifinfo, fib uint64
+	// marked dumps awaiting their break
+	marks []marked
+}
+
+type marked struct {
+	Mark
+	gen uint64 // of the dump, or zero if not swept
+}
+
+// a dump written to the driver that awaits its mark note
+type pendingMark struct {
+	Mark
+	sweep bool
+}
+
+const sizeofMsgMark = internal.SizeofMsg + 8
+
+func ifinfoGeneration() uint64 {
+	generation.Lock()
+	defer generation.Unlock()
+	return generation.ifinfo
+}
+
+func fibGeneration() uint64 {
+	generation.Lock()
+	defer generation.Unlock()
+	return generation.fib
+}
+
+func mark(m Mark, sweep bool) Mark {
+	generation.Lock()
+	defer generation.Unlock()
+	var gen uint64
+	switch {
+	case !sweep:
+	case m == MarkIfInfo:
+		generation.ifinfo++
+		gen = generation.ifinfo
+	case m == MarkFib:
+		generation.fib++
+		gen = generation.fib
+	}
+	generation.marks = append(generation.marks, marked{m, gen})
+	return m
+}
+
+// forget the marked dumps of a closed socket; there won't be a break
+func unmark() {
+	generation.Lock()
+	defer generation.Unlock()
+	generation.marks = generation.marks[:0]
+}
+
+// sweep the stale entries of the oldest marked dump
+func sweep() (notes []interface{}) {
+	generation.Lock()
+	if len(generation.marks) == 0 {
+		generation.Unlock()
+		return
+	}
+	m := generation.marks[0]
+	copy(generation.marks, generation.marks[1:])
+	generation.marks = generation.marks[:len(generation.marks)-1]
+	generation.Unlock()
+	switch {
+	case m.gen == 0:
+	case m.Mark == MarkIfInfo:
+		notes = sweepLinks(m.gen)
+	case m.Mark == MarkFib:
+		notes = sweepFib(m.gen)
+	}
+	return
+}
+
+// queue the marks of the written dumps before the next received message so
+// that each precedes its dump; returns false if the task is done.
+func (task *Task) sendMarks() bool {
+	task.marks.Lock()
+	pending := task.marks.pending
+	task.marks.pending = nil
+	task.marks.Unlock()
+	for _, m := range pending {
+		buf := newBuffer(sizeofMsgMark)
+		h := (*internal.MsgHeader)(buf.pointer())
+		switch m.Mark {
+		case MarkIfInfo:
+			h.SetLocal(internal.MsgKindMarkIfInfo)
+		case MarkFib:
+			h.SetLocal(internal.MsgKindMarkFib)
+		}
+		b := buf.bytes()[internal.SizeofMsg:]
+		for i := range b {
+			b[i] = 0
+		}
+		if m.sweep {
+			b[0] = 1
+		}
+		if task.rec != nil {
+			task.rec.Msg(buf.bytes(), DirectionIn)
+		}
+		if !task.send(buf) {
+			return false
+		}
+	}
+	return true
+}
+
+// record the mark of a dump after writing it with task.marks held
+func (task *Task) pendMark(kind uint8) {
+	switch kind {
+	case internal.MsgKindDumpIfInfo:
+		task.marks.pending = append(task.marks.pending,
+			pendingMark{MarkIfInfo, true})
+	case internal.MsgKindDumpFibInfo:
+		task.marks.pending = append(task.marks.pending,
+			pendingMark{MarkFib, !task.marks.fib})
+		task.marks.fib = true
+	}
+}
+
+// forget the pending marks and fib dump of a closed session
+func (task *Task) resetMarks() {
+	task.marks.Lock()
+	task.marks.pending = nil
+	task.marks.fib = false
+	task.marks.Unlock()
+}
+
+// whether the marked dump is swept
+func rxMarkSweep(buf buffer) bool {
+	b := buf.bytes()
+	return len(b) < sizeofMsgMark || b[internal.SizeofMsg] != 0
+}
+
+func sweepLinks(gen uint64) (notes []interface{}) {
+	var stale []Xid
+	LinkRange(func(xid Xid, l *Link) bool {
+		if atomic.LoadUint64(&l.dumpgen) < gen {
+			stale = append(stale, xid)
+		}
+		return true
+	})
+	for _, xid := range stale {
+		l := LinkOf(xid)
+		for _, upper := range l.Uppers() {
+			if ul := LinkOf(upper); ul != nil {
+				ul.Lowers(xid.Delist(ul.Lowers()))
+			}
+		}
+		for _, lower := range l.Lowers() {
+			if ll := LinkOf(lower); ll != nil {
+				ll.Uppers(xid.Delist(ll.Uppers()))
+			}
+		}
+		ns, ifindex := l.IfInfoNetNs(), l.IfInfoIfIndex()
+		if ns != 0 && ns.Xid(ifindex) == xid {
+			ns.Xid(ifindex, 0)
+		}
+		notes = append(notes, RxDelete(xid))
+	}
+	if len(stale) > 0 {
+		notes = append(notes, sweepNeighbors()...)
+	}
+	return
+}
+
+func sweepFib(gen uint64) (notes []interface{}) {
+	NetNsRange(func(ns NetNs) bool {
+		for _, rt := range ns.RtTables() {
+			var stale []*FibEntry
+			ns.FibEntries(rt, func(fe *FibEntry) bool {
+				if fe.dumpgen < gen {
+					stale = append(stale, fe)
+				}
+				return true
+			})
+			for _, fe := range stale {
+				del := fe.clone()
+				del.FibEntryEvent = FIB_EVENT_ENTRY_DEL
+				ns.fibentry(del)
+				notes = append(notes, del)
+			}
+		}
+		return true
+	})
+	return append(notes, sweepNeighbors()...)
+}
+
+// The driver doesn't replay neighbors with a fib dump, so sweep those that
+// refer to links that are gone rather than those not refreshed.
+func sweepNeighbors() (notes []interface{}) {
+	NetNsRange(func(ns NetNs) bool {
+		var stale []*Neighbor
+		ns.Neighbors(func(neigh *Neighbor) bool {
+			if neigh.Xid != 0 && !Valid(neigh.Xid) {
+				stale = append(stale, neigh)
+			}
+			return true
+		})
+		for _, neigh := range stale {
+			del := neigh.clone()
+			for i := range del.HardwareAddr {
+				del.HardwareAddr[i] = 0
+			}
+			ns.neighbor(del)
+			notes = append(notes, del)
+		}
+		return true
+	})
+	return
+}
+
+// Pool the swept notes.
+func (b Break) Pool() {
+	for _, note := range b.Swept {
+		Pool(note)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/xethtest"
+)
+
+func TestSweepLinks(t *testing.T) {
+	mux, task := startTask(t)
+	ctx := context.Background()
+	mux.IfInfo(
+		xethtest.MsgIfInfo(51, "xeth51", 51, xeth.DevKindPort,
+			xethtest.ReasonDump, testHa),
+		xethtest.MsgIfInfo(52, "xeth52", 52, xeth.DevKindPort,
+			xethtest.ReasonDump, testHa))
+	if err := task.DumpIfInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	untilBreak(t, task)
+	mux.IfInfo(xethtest.MsgIfInfo(51, "xeth51", 51, xeth.DevKindPort,
+		xethtest.ReasonDump, testHa))
+	// both marks precede their dumps
+	if err := task.DumpIfInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := task.DumpIfInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		notes := untilBreak(t, task)
+		br := notes[len(notes)-1].(xeth.Break)
+		if i == 0 {
+			if len(br.Swept) != 1 || br.Swept[0] != xeth.DevDel(52) {
+				t.Error("swept", br.Swept)
+			}
+		} else if len(br.Swept) != 0 {
+			t.Error("swept again", br.Swept)
+		}
+	}
+	if xeth.LinkOf(51) == nil || xeth.LinkOf(52) != nil {
+		t.Error("links", xeth.ListXids())
+	}
+}
+
+func TestSweepFib(t *testing.T) {
+	mux, task := startTask(t,
+		xeth.Reconnect(10*time.Millisecond, 50*time.Millisecond))
+	ctx := context.Background()
+	_, ipnet, _ := net.ParseCIDR("10.5.0.0/24")
+	mux.FibInfo(xethtest.MsgFibEntry(xeth.DefaultNetNs, ipnet,
+		xeth.MainRtTable, xeth.FIB_EVENT_ENTRY_REPLACE, xeth.RTN_UNICAST))
+	if err := task.DumpFib(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if notes := untilBreak(t, task); len(notes) != 3 {
+		t.Fatal("dump", notes)
+	}
+	// the driver doesn't replay the fib again this session
+	if err := task.DumpFib(ctx); err != nil {
+		t.Fatal(err)
+	}
+	notes := untilBreak(t, task)
+	if len(notes) != 2 || notes[0] != xeth.MarkFib {
+		t.Fatal("redump", notes)
+	}
+	if br := notes[1].(xeth.Break); len(br.Swept) != 0 {
+		t.Error("swept", br.Swept)
+	}
+	if n := fibEntries(); n != 1 {
+		t.Fatal("entries", n)
+	}
+	// but does after reconnect
+	mux.FibInfo()
+	mux.Disconnect()
+	for {
+		if _, ok := next(t, task).(xeth.Resynced); ok {
+			break
+		}
+	}
+	if n := fibEntries(); n != 0 {
+		t.Error("stale entries", n)
+	}
+}
+
+func fibEntries() (n int) {
+	xeth.DefaultNetNs.FibEntries(xeth.MainRtTable,
+		func(*xeth.FibEntry) bool {
+			n++
+			return true
+		})
+	return
+}
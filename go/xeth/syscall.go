@@ -0,0 +1,16 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+//go:build !amd64 && !386
+// +build !amd64,!386
+
+package xeth
+
+import "syscall"
+
+const (
+	sysSendmmsg   = syscall.SYS_SENDMMSG
+	sysGetsockopt = syscall.SYS_GETSOCKOPT
+	sysSetsockopt = syscall.SYS_SETSOCKOPT
+)
@@ -0,0 +1,13 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+// syscall multiplexes 386 socket calls through socketcall; these are the
+// direct calls of linux 4.3 and later
+const (
+	sysSendmmsg   = 345
+	sysGetsockopt = 365
+	sysSetsockopt = 366
+)
@@ -0,0 +1,14 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import "syscall"
+
+const (
+	// syscall doesn't define SYS_SENDMMSG for amd64
+	sysSendmmsg   = 307
+	sysGetsockopt = syscall.SYS_GETSOCKOPT
+	sysSetsockopt = syscall.SYS_SETSOCKOPT
+)
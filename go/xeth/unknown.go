@@ -0,0 +1,71 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// UnknownMsg notes a message of a kind that this package doesn't parse and
+// that has no registered Decoder, e.g. one added by a newer driver.
+// Bytes is a copy of the whole message, header included.
+type UnknownMsg struct {
+	Kind  MsgKind
+	Bytes []byte
+}
+
+// Decoder returns the note of a vendor specific message. The given bytes are
+// a copy of the whole message, header included, that the decoder may keep.
+type Decoder func(kind MsgKind, b []byte) interface{}
+
+var decoders struct {
+	sync.RWMutex
+	m map[MsgKind]Decoder
+}
+
+// RegisterDecoder plugs in the Parse of a kind that this package doesn't
+// parse itself. It fails if the kind is known or already registered. The
+// task local kinds may have decoders of side-band messages.
+func RegisterDecoder(kind MsgKind, decoder Decoder) error {
+	_, found := msgKindNames[kind]
+	if found && kind < internal.MinLocalMsgKind {
+		return fmt.Errorf("xeth %v msg isn't a vendor kind", kind)
+	}
+	decoders.Lock()
+	defer decoders.Unlock()
+	if _, found := decoders.m[kind]; found {
+		return fmt.Errorf("xeth %v msg already has a decoder", kind)
+	}
+	if decoders.m == nil {
+		decoders.m = make(map[MsgKind]Decoder)
+	}
+	decoders.m[kind] = decoder
+	return nil
+}
+
+// UnregisterDecoder of the given kind; Parse will then note its messages as
+// UnknownMsg.
+func UnregisterDecoder(kind MsgKind) {
+	decoders.Lock()
+	defer decoders.Unlock()
+	delete(decoders.m, kind)
+}
+
+func unknown(buf buffer) interface{} {
+	b := buf.bytes()
+	kind := MsgKind(kind(buf))
+	decoders.RLock()
+	decoder := decoders.m[kind]
+	decoders.RUnlock()
+	bytes := make([]byte, len(b))
+	copy(bytes, b)
+	if decoder != nil {
+		return decoder(kind, bytes)
+	}
+	return UnknownMsg{kind, bytes}
+}
@@ -0,0 +1,49 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"testing"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+func TestLocalKinds(t *testing.T) {
+	msg := func(kind uint8, local bool) buffer {
+		buf := newBuffer(internal.SizeofMsg)
+		h := (*internal.MsgHeader)(buf.pointer())
+		if local {
+			h.SetLocal(kind)
+		} else {
+			h.Set(kind)
+		}
+		return buf
+	}
+	if _, ok := Parse(msg(internal.MsgKindResynced, true)).(Resynced); !ok {
+		t.Error("local note isn't resynced")
+	}
+	for _, kind := range []uint8{
+		internal.MsgKindDisconnected,
+		internal.MsgKindOverrun,
+	} {
+		v, ok := Parse(msg(kind, false)).(UnknownMsg)
+		if !ok || v.Kind != MsgKind(kind) {
+			t.Errorf("side-band %v: %#v", MsgKind(kind), v)
+		}
+	}
+	type decoded struct{}
+	err := RegisterDecoder(MsgKindOverrun,
+		func(MsgKind, []byte) interface{} { return decoded{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterDecoder(MsgKindOverrun)
+	if _, ok := Parse(msg(internal.MsgKindOverrun, false)).(decoded); !ok {
+		t.Error("side-band overrun isn't decoded")
+	}
+	if err = RegisterDecoder(MsgKindBreak, nil); err == nil {
+		t.Error("registered break")
+	}
+}
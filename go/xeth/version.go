@@ -0,0 +1,76 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// The task encodes and decodes side-band messages of these protocol
+// versions. Version 3 adds the EthtoolPause, EthtoolFec, and EthtoolEee
+// kinds to version 2; the task drops these rather than send them in
+// version 2.
+const (
+	MinProtocolVersion = internal.MinMsgVersion
+	MaxProtocolVersion = internal.MsgVersion
+)
+
+// PinProtocolVersion sets the version of the messages sent to the driver
+// rather than negotiate it. The task fails on any received message of
+// another version.
+func PinProtocolVersion(version uint8) TaskOption {
+	return func(task *Task) {
+		task.version = uint32(version)
+		task.pinned = true
+	}
+}
+
+// ProtocolVersion returns the negotiated version of the side-band messages.
+//
+// The task begins each connection with MaxProtocolVersion then adopts the
+// version of the first message that it receives. The driver closes a
+// connection that sends a version that it doesn't speak, so if this happens
+// before any reply, the task redials with the previous version.
+func (task *Task) ProtocolVersion() uint8 {
+	return uint8(atomic.LoadUint32(&task.version))
+}
+
+func (task *Task) checkVersion() error {
+	if v := task.ProtocolVersion(); v < MinProtocolVersion ||
+		v > MaxProtocolVersion {
+		return fmt.Errorf("xeth protocol version %d, expect %d to %d",
+			v, MinProtocolVersion, MaxProtocolVersion)
+	}
+	return nil
+}
+
+// adopt the version of the first message received after connect; thereafter,
+// or if pinned, fail on any other version
+func (task *Task) matchVersion(version uint8, first bool) error {
+	if first && !task.pinned {
+		atomic.StoreUint32(&task.version, uint32(version))
+		return nil
+	}
+	if expect := task.ProtocolVersion(); version != expect {
+		return fmt.Errorf("msg version %d, expect %d", version, expect)
+	}
+	return nil
+}
+
+// fallback is true if the driver closed the connection after the task sent
+// it something but before any reply, and there's an older version to try
+func (task *Task) fallback(err error, heard bool) bool {
+	return isDisconnect(err) && !heard && !task.pinned &&
+		atomic.LoadUint32(&task.spoke) != 0 &&
+		task.ProtocolVersion() > MinProtocolVersion
+}
+
+// speaks is true if the negotiated version has the given kind of message
+func (task *Task) speaks(kind uint8) bool {
+	return task.ProtocolVersion() >= internal.KindVersion(kind)
+}
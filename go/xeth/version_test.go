@@ -0,0 +1,114 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/xethtest"
+)
+
+func TestProtocolVersion(t *testing.T) {
+	mux, task := startTask(t)
+	ctx := context.Background()
+	if err := task.DumpIfInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	untilBreak(t, task)
+	if v := task.ProtocolVersion(); v != xeth.MaxProtocolVersion {
+		t.Error("version", v)
+	}
+	err := task.SetPause(ctx, 3, xeth.EthtoolPause{Rx: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		_, found := mux.Pause(3)
+		return found
+	})
+}
+
+func TestProtocolFallback(t *testing.T) {
+	del, err := xethtest.Veth(testMux, testPeer)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer del()
+	mux, err := xethtest.New(testMux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mux.Close()
+	mux.Version = xeth.MinProtocolVersion // an older driver
+	ctx := context.Background()
+	task, err := xeth.StartContext(ctx, testMux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer task.Close()
+	if !mux.Accepted() {
+		t.Fatal("not accepted")
+	}
+	// the mux closes the first connection then answers the reissued dump
+	if err = task.DumpIfInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	untilBreak(t, task)
+	if v := task.ProtocolVersion(); v != xeth.MinProtocolVersion {
+		t.Error("version", v)
+	}
+	// version 2 lacks pause
+	err = task.SetPause(ctx, 3, xeth.EthtoolPause{Rx: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = task.SetSpeed(ctx, 3, 10000); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		_, found := mux.Speed(3)
+		return found
+	})
+	if _, found := mux.Pause(3); found {
+		t.Error("sent pause")
+	}
+	for _, msg := range mux.Received() {
+		if v := xethtest.VersionOf(msg); v != xeth.MinProtocolVersion {
+			t.Error("sent version", v)
+		}
+	}
+	if err = task.Close(); err != nil {
+		t.Error("close", err)
+	}
+
+	// without fallback, the task fails when the driver closes the socket
+	task, err = xeth.StartContext(ctx, testMux,
+		xeth.PinProtocolVersion(xeth.MaxProtocolVersion))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = task.DumpIfInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-task.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("pinned version accepted")
+	}
+	var te *xeth.TaskError
+	if err = task.Close(); !errors.As(err, &te) || te.Rx == nil {
+		t.Error("close", err)
+	}
+
+	_, err = xeth.StartContext(ctx, testMux,
+		xeth.PinProtocolVersion(xeth.MinProtocolVersion-1))
+	if err == nil {
+		t.Error("pinned unsupported version")
+	}
+}
@@ -7,6 +7,7 @@
 package xeth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -15,6 +16,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
@@ -28,31 +30,102 @@ import (
 
 const unixpacket = "unixpacket"
 
-type Break struct{}
+// Break ends a dump. If the task marked the dump, Swept has the synthetic
+// DevDel, fib delete and neighbor delete notes of the entries that the dump
+// didn't refresh.
+type Break struct {
+	Swept []interface{}
+}
 
 var (
-	Cloned  Counter // cloned received messages
-	Parsed  Counter // messages parsed by user
-	Dropped Counter // messages that overflowed transmit channel
-	Sent    Counter // messages and exception frames sent to driver
-	Unknown Counter // LinkOf xid w/o IfInfo
+	Cloned    Counter // cloned received messages
+	Parsed    Counter // messages parsed by user
+	Dropped   Counter // messages that overflowed transmit channel
+	Sent      Counter // messages and exception frames sent to driver
+	Unknown   Counter // LinkOf xid w/o IfInfo
+	Coalesced Counter // stats superseded before sent to driver
 )
 
+// ErrStopped is returned by the senders of a task that stopped without
+// failure; those of a failed task return its *TaskError.
+var ErrStopped = errors.New("xeth task stopped")
+
 type Task struct {
 	RxCh <-chan Buffer // cloned msgs received from driver
 
-	WG   *sync.WaitGroup
+	// Stop is closed with Done when the task stops.
 	Stop <-chan struct{}
-	sock *net.UnixConn
-
-	loch chan<- buffer // low priority, leaky-bucket tx channel
-	hich chan<- buffer // high priority, unbuffered, no-drop tx channel
 
+	// Deprecated: WG, RxErr, and TxErr are only set by Start;
+	// StartContext users should call Close and Err instead.
+	WG    *sync.WaitGroup
 	RxErr error // error that stopped the rx service
 	TxErr error // error that stopped the tx service
 
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup // all service routines
+	svcwg  sync.WaitGroup // rx, raw rx, and tx service routines
+
+	mutex   sync.Mutex
+	sock    *net.UnixConn
+	rxerr   error
+	txerr   error
+	dumpfib bool
+
+	// dumps written but not yet marked in RxCh
+	marks struct {
+		sync.Mutex
+		pending []pendingMark
+		fib     bool // dumped this session
+	}
+
+	mux        string
+	atsockaddr *net.UnixAddr
+	redial     struct{ min, max time.Duration }
+
+	rxch chan Buffer
+	loch chan<- buffer // low priority, leaky-bucket tx channel
+	hich chan<- buffer // high priority, no-drop tx channel
+
 	muxfd int
 	muxsa syscall.SockaddrLinklayer
+
+	rxdepth  int
+	rxpolicy RxPolicy
+	overrun  overrun
+
+	rawfilter []syscall.SockFilter
+	rawring   struct{ blockSize, blocks int }
+	ring      *ring
+
+	stats     stattx
+	statNames statNames
+	metrics   Metrics
+
+	rec *Recorder
+
+	dampening *dampening
+
+	version uint32 // atomic, negotiated protocol version
+	pinned  bool
+	spoke   uint32 // atomic, nonzero after a send to this connection
+}
+
+// TaskOption configures the Task created by StartContext.
+type TaskOption func(*Task)
+
+// TaskError combines the errors that stopped the task's rx and tx services.
+type TaskError struct {
+	Rx error
+	Tx error
+}
+
+// RxDepth sets the RxCh buffer depth, default 1024.
+func RxDepth(n int) TaskOption {
+	return func(task *Task) {
+		task.rxdepth = n
+	}
 }
 
 // Write provision value to platform device sysfs file
@@ -80,9 +153,40 @@ func Provision(dev, val string) error {
 	return err
 }
 
-// Connect socket and run channel service routines.
+// Connect socket and run channel service routines until stop is closed.
+// This wraps StartContext for those that wait on the given group then check
+// the task's RxErr and TxErr.
 func Start(mux string, wg *sync.WaitGroup,
 	stop <-chan struct{}) (task *Task, err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-stop:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+	// set WG before goClose may read it
+	task, err = StartContext(ctx, mux, func(task *Task) { task.WG = wg })
+	if err != nil {
+		cancel()
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		<-task.Done()
+		task.wg.Wait()
+	}()
+	return
+}
+
+// Connect socket and run channel service routines until the context is
+// done, the driver closes the socket, or a service fails.
+// The caller should Close the returned task.
+func StartContext(ctx context.Context, mux string,
+	opts ...TaskOption) (task *Task, err error) {
 	muxif, err := net.InterfaceByName(mux)
 	if err != nil {
 		return
@@ -102,70 +206,191 @@ func Start(mux string, wg *sync.WaitGroup,
 		syscall.Close(muxfd)
 		return
 	}
-
-	atsock, err := func(a *net.UnixAddr) (*net.UnixConn, error) {
-		t := time.NewTicker(100 * time.Millisecond)
-		defer t.Stop()
-		for {
-			s, err := net.DialUnix(unixpacket, nil, atsockaddr)
-			if err == nil {
-				return s, nil
-			} else if !isEAGAIN(err) &&
-				!errors.Is(err, syscall.ECONNREFUSED) {
-				return nil, err
-			}
-			select {
-			case <-stop:
-				return nil, io.EOF
-			case <-t.C:
-			}
-		}
-	}(atsockaddr)
+	atsock, err := dial(ctx, atsockaddr, minRedial, minRedial)
+	if err != nil {
+		syscall.Close(muxfd)
+		return
+	}
 
 	loch := make(chan buffer, 4)
 	hich := make(chan buffer, 4)
-	rxch := make(chan Buffer, 1024)
 
 	task = &Task{
-		RxCh:  rxch,
-		WG:    wg,
-		Stop:  stop,
-		sock:  atsock,
-		loch:  loch,
-		hich:  hich,
-		muxfd: muxfd,
+		sock:       atsock,
+		mux:        mux,
+		atsockaddr: atsockaddr,
+		loch:       loch,
+		hich:       hich,
+		muxfd:      muxfd,
 		muxsa: syscall.SockaddrLinklayer{
 			Protocol: syscall.ETH_P_ARP,
 			Ifindex:  muxif.Index,
 			Hatype:   syscall.ARPHRD_ETHER,
 		},
+		rxdepth: 1024,
+		version: MaxProtocolVersion,
+	}
+	task.metrics.task = task
+	task.stats.init()
+	task.rawring.blockSize = defaultRingBlockSize
+	task.rawring.blocks = defaultRingBlocks
+	for _, opt := range opts {
+		opt(task)
 	}
+	if err = task.checkVersion(); err == nil {
+		err = task.setupRaw()
+	}
+	if err != nil {
+		atsock.Close()
+		syscall.Close(muxfd)
+		task = nil
+		return
+	}
+	task.ctx, task.cancel = context.WithCancel(ctx)
+	task.Stop = task.ctx.Done()
+
+	rxch := make(chan Buffer, task.rxdepth)
+	task.RxCh = rxch
+	task.rxch = rxch
 
-	task.WG.Add(4)
-	go task.goRx(rxch)
+	task.wg.Add(4)
+	task.svcwg.Add(3)
+	go task.goRx()
 	go task.goTx(loch, hich)
-	go task.goRawRx(rxch)
-	go task.goClose()
+	if task.ring != nil {
+		go task.goRingRx()
+	} else {
+		go task.goRawRx()
+	}
+	go task.goClose(rxch)
 
 	return
 }
 
+// Close stops the task then waits for its service routines to exit.
+// It returns nil if the task stopped w/o failure, otherwise a *TaskError.
+func (task *Task) Close() error {
+	task.cancel()
+	task.wg.Wait()
+	return task.failure()
+}
+
+// Done is closed when the task stops.
+func (task *Task) Done() <-chan struct{} {
+	return task.ctx.Done()
+}
+
+// Err returns nil while the task runs. After Done, Err returns a *TaskError
+// with the cause of failure or, if stopped without failure, the context's
+// error.  The driver closing the socket results in an io.EOF rx error.
+func (task *Task) Err() error {
+	select {
+	case <-task.ctx.Done():
+	default:
+		return nil
+	}
+	if err := task.failure(); err != nil {
+		return err
+	}
+	return task.ctx.Err()
+}
+
+func (task *Task) failure() error {
+	task.mutex.Lock()
+	defer task.mutex.Unlock()
+	if task.rxerr == nil && task.txerr == nil {
+		return nil
+	}
+	return &TaskError{Rx: task.rxerr, Tx: task.txerr}
+}
+
+// record the first error of a service then stop the task; ignore errors
+// after the task is stopped since these result from closing its sockets
+func (task *Task) fail(perr *error, err error) {
+	if err == nil {
+		return
+	}
+	task.mutex.Lock()
+	if task.ctx.Err() == nil && *perr == nil {
+		*perr = err
+	}
+	task.mutex.Unlock()
+	task.cancel()
+}
+
+func (err *TaskError) Error() string {
+	switch {
+	case err.Rx != nil && err.Tx != nil:
+		return fmt.Sprint("rx: ", err.Rx, "; tx: ", err.Tx)
+	case err.Rx != nil:
+		return fmt.Sprint("rx: ", err.Rx)
+	default:
+		return fmt.Sprint("tx: ", err.Tx)
+	}
+}
+
+// Unwrap returns the rx error, if any, otherwise the tx error.
+func (err *TaskError) Unwrap() error {
+	if err.Rx != nil {
+		return err.Rx
+	}
+	return err.Tx
+}
+
 func kind(buf buffer) uint8 {
 	return (*internal.MsgHeader)(buf.pointer()).Kind
 }
 
+func isLocal(buf buffer) bool {
+	return (*internal.MsgHeader)(buf.pointer()).IsLocal()
+}
+
+// ParseError notes a message that Parse rejected rather than read beyond
+// its buffer. Kind is zero if the buffer is too short for a header.
+type ParseError struct {
+	Kind   MsgKind
+	Len    int
+	Reason string
+}
+
+func (err ParseError) Error() string {
+	if err.Len < internal.SizeofMsg {
+		return fmt.Sprintf("xeth msg, length %d: %s", err.Len, err.Reason)
+	}
+	return fmt.Sprintf("xeth %v msg, length %d: %s",
+		err.Kind, err.Len, err.Reason)
+}
+
 // parse driver message and cache ifinfo in xid maps.
 func Parse(buf Buffer) interface{} {
 	defer Parsed.Inc()
-	for _, b := range buf.bytes()[:14] {
-		if b != 0 {
-			return Frame{buf}
-		}
+	if isFrame(buf) {
+		return Frame{buf}
 	}
 	defer buf.pool()
+	if err := internal.CheckLen(buf.bytes()); err != nil {
+		return ParseError{KindOf(buf), len(buf.bytes()), err.Error()}
+	}
+	if isLocal(buf) != (kind(buf) >= internal.MinLocalMsgKind) {
+		// a side-band message of a local kind or vice versa
+		return unknown(buf)
+	}
 	switch k := kind(buf); k {
 	case internal.MsgKindBreak:
-		return Break{}
+		return Break{sweep()}
+	case internal.MsgKindDisconnected:
+		unmark()
+		return Disconnected{}
+	case internal.MsgKindResynced:
+		return Resynced{}
+	case internal.MsgKindMarkIfInfo:
+		return mark(MarkIfInfo, rxMarkSweep(buf))
+	case internal.MsgKindMarkFib:
+		return mark(MarkFib, rxMarkSweep(buf))
+	case internal.MsgKindOverrun:
+		return rxOverrun(buf)
+	case internal.MsgKindCarrierDampened:
+		return rxCarrierDampened(buf)
 	case internal.MsgKindChangeUpperXid:
 		msg := (*internal.MsgChangeUpperXid)(buf.pointer())
 		lower := Xid(msg.Lower)
@@ -180,16 +405,28 @@ func Parse(buf Buffer) interface{} {
 		return Xid(msg.Xid).RxEthtoolFlags(msg.Flags)
 	case internal.MsgKindEthtoolLinkModesSupported:
 		msg := (*internal.MsgEthtoolLinkModes)(buf.pointer())
-		return Xid(msg.Xid).RxSupported(msg.Modes)
+		return Xid(msg.Xid).RxSupported(
+			msg.Words(len(buf.bytes()))...)
 	case internal.MsgKindEthtoolLinkModesAdvertising:
 		msg := (*internal.MsgEthtoolLinkModes)(buf.pointer())
-		return Xid(msg.Xid).RxAdvertising(msg.Modes)
+		return Xid(msg.Xid).RxAdvertising(
+			msg.Words(len(buf.bytes()))...)
 	case internal.MsgKindEthtoolLinkModesLPAdvertising:
 		msg := (*internal.MsgEthtoolLinkModes)(buf.pointer())
-		return Xid(msg.Xid).RxLPAdvertising(msg.Modes)
+		return Xid(msg.Xid).RxLPAdvertising(
+			msg.Words(len(buf.bytes()))...)
 	case internal.MsgKindEthtoolSettings:
 		msg := (*internal.MsgEthtoolSettings)(buf.pointer())
 		return Xid(msg.Xid).RxEthtoolSettings(msg)
+	case internal.MsgKindEthtoolPause:
+		msg := (*internal.MsgEthtoolPause)(buf.pointer())
+		return Xid(msg.Xid).RxEthtoolPause(msg)
+	case internal.MsgKindEthtoolFec:
+		msg := (*internal.MsgEthtoolFec)(buf.pointer())
+		return Xid(msg.Xid).RxEthtoolFec(msg)
+	case internal.MsgKindEthtoolEee:
+		msg := (*internal.MsgEthtoolEee)(buf.pointer())
+		return Xid(msg.Xid).RxEthtoolEee(msg)
 	case internal.MsgKindFibEntry:
 		msg := (*internal.MsgFibEntry)(buf.pointer())
 		return fib4(msg)
@@ -244,6 +481,8 @@ func Parse(buf Buffer) interface{} {
 	case internal.MsgKindNetNsDel:
 		msg := (*internal.MsgNetNs)(buf.pointer())
 		return NetNsDel{NetNs(msg.Net)}
+	default:
+		return unknown(buf)
 	}
 	return nil
 }
@@ -258,19 +497,22 @@ func Pool(v interface{}) {
 }
 
 // request fib dump
-func (task *Task) DumpFib() {
+func (task *Task) DumpFib(ctx context.Context) error {
+	task.mutex.Lock()
+	task.dumpfib = true
+	task.mutex.Unlock()
 	buf := newBuffer(internal.SizeofMsgDumpFibInfo)
 	msg := (*internal.MsgHeader)(buf.pointer())
 	msg.Set(internal.MsgKindDumpFibInfo)
-	task.hich <- buf
+	return task.hi(ctx, buf)
 }
 
 // request ifinfo dump
-func (task *Task) DumpIfInfo() {
+func (task *Task) DumpIfInfo(ctx context.Context) error {
 	buf := newBuffer(internal.SizeofMsgDumpIfInfo)
 	msg := (*internal.MsgHeader)(buf.pointer())
 	msg.Set(internal.MsgKindDumpIfInfo)
-	task.hich <- buf
+	return task.hi(ctx, buf)
 }
 
 // Send an exception frame to driver through raw socket.
@@ -278,103 +520,126 @@ func (task *Task) ExceptionFrame(b []byte) {
 	// set priority so that the xeth will forward to the
 	// respective upper device rather than it's port
 	b[ETH_VLAN_TCI] |= VlanPrioMask >> 8
-	syscall.Sendto(task.muxfd, b, 0, &task.muxsa)
-}
-
-// Send carrier change to driver through hi-priority channel.
-func (task *Task) SetCarrier(xid Xid, on bool) {
-	buf := newBuffer(internal.SizeofMsgCarrier)
-	msg := (*internal.MsgCarrier)(buf.pointer())
-	msg.Header.Set(internal.MsgKindCarrier)
-	msg.Xid = uint32(xid)
-	if on {
-		msg.Flag = internal.CarrierOn
-	} else {
-		msg.Flag = internal.CarrierOff
+	if syscall.Sendto(task.muxfd, b, 0, &task.muxsa) == nil {
+		task.metrics.tx(MsgKindFrame)
+		if task.rec != nil {
+			task.rec.Frame(b, DirectionOut)
+		}
 	}
-	task.hich <- buf
 }
 
-// Send ethtool stat change to driver through leaky-bucket channel.
-func (task *Task) SetEthtoolStat(xid Xid, stat uint32, n uint64) {
-	task.setStat(internal.MsgKindEthtoolStat, xid, stat, n)
+// Send ethtool stat change to driver in a batch of latest stats.
+func (task *Task) SetEthtoolStat(ctx context.Context, xid Xid, stat uint32,
+	n uint64) error {
+	return task.setStat(ctx, internal.MsgKindEthtoolStat, xid, stat, n)
 }
 
-// Send link stat change to driver through leaky-bucket channel.
-func (task *Task) SetLinkStat(xid Xid, stat uint32, n uint64) {
-	task.setStat(internal.MsgKindLinkStat, xid, stat, n)
-}
-
-func (task *Task) setStat(kind uint8, xid Xid, stat uint32, n uint64) {
-	buf := newBuffer(internal.SizeofMsgStat)
-	msg := (*internal.MsgStat)(buf.pointer())
-	msg.Header.Set(kind)
-	msg.Xid = uint32(xid)
-	msg.Index = stat
-	msg.Count = n
-	task.hich <- buf
+// Send link stat change to driver in a batch of latest stats.
+func (task *Task) SetLinkStat(ctx context.Context, xid Xid, stat LinkStat,
+	n uint64) error {
+	return task.setStat(ctx, internal.MsgKindLinkStat, xid, uint32(stat), n)
 }
 
 // Send speed change to driver through hi-priority channel.
-func (task *Task) SetSpeed(xid Xid, mbps uint32) {
+func (task *Task) SetSpeed(ctx context.Context, xid Xid, mbps uint32) error {
 	buf := newBuffer(internal.SizeofMsgSpeed)
 	msg := (*internal.MsgSpeed)(buf.pointer())
 	msg.Header.Set(internal.MsgKindSpeed)
 	msg.Xid = uint32(xid)
 	msg.Mbps = mbps
-	task.hich <- buf
+	return task.hi(ctx, buf)
 }
 
-// Wait for stop signal then shutdown and close socket
-func (task *Task) goClose() {
-	defer task.WG.Done()
+// Wait for stop signal then shutdown socket. After the other service routines
+// exit, close the receive channel and sockets.
+func (task *Task) goClose(rxch chan<- Buffer) {
+	defer task.wg.Done()
 
-	<-task.Stop
+	<-task.ctx.Done()
 
-	if task.muxfd > 0 {
-		syscall.Close(task.muxfd)
+	task.mutex.Lock()
+	sock := task.sock
+	task.mutex.Unlock()
+	if rc, err := sock.SyscallConn(); err == nil {
+		rc.Control(func(fd uintptr) {
+			syscall.Shutdown(int(fd), SHUT_RDWR)
+		})
 	}
-	if task.sock == nil {
-		return
+
+	if task.ring != nil {
+		task.ring.stop()
 	}
-	sock := task.sock
+
+	task.svcwg.Wait()
+
+	task.mutex.Lock()
+	// rx may have redialed
+	sock = task.sock
 	task.sock = nil
-	f, err := sock.File()
-	if err == nil {
-		syscall.Shutdown(int(f.Fd()), SHUT_RDWR)
+	if task.WG != nil {
+		if task.rxerr != io.EOF {
+			task.RxErr = task.rxerr
+		}
+		task.TxErr = task.txerr
 	}
+	task.mutex.Unlock()
+
+	if task.dampening != nil {
+		task.dampening.close()
+	}
+	close(rxch)
 	sock.Close()
+	if task.ring != nil {
+		task.ring.close()
+	}
+	if task.muxfd > 0 {
+		syscall.Close(task.muxfd)
+	}
 }
 
-func (task *Task) goRawRx(rxch chan<- Buffer) {
-	defer task.WG.Done()
+func (task *Task) goRawRx() {
+	defer task.wg.Done()
+	defer task.svcwg.Done()
 
 	rxbuf := make([]byte, internal.SizeofJumboFrame)
 	for {
 		select {
-		case <-task.Stop:
+		case <-task.ctx.Done():
 			return
 		default:
 		}
-		// FIXME timeout through sockopt or select
 		n, from, err := syscall.Recvfrom(task.muxfd, rxbuf, 0)
-		if err != nil {
-			e, ok := err.(*os.SyscallError)
-			if !ok || e.Err.Error() != "EOF" {
-				task.RxErr = err
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			continue
+		} else if err == syscall.ENETDOWN && task.redial.max > 0 {
+			// admin-down mux; wait for it to come back up
+			select {
+			case <-task.ctx.Done():
+				return
+			case <-time.After(task.redial.min):
 			}
+			continue
+		} else if err != nil {
+			task.fail(&task.rxerr, err)
 			return
 		}
 		sa, ok := from.(*syscall.SockaddrLinklayer)
 		if ok && sa.Ifindex == task.muxsa.Ifindex {
-			rxch <- cloneBuffer(rxbuf[:n])
+			task.metrics.rx(MsgKindFrame)
+			if task.rec != nil {
+				task.rec.Frame(rxbuf[:n], DirectionIn)
+			}
+			if !task.send(cloneBuffer(rxbuf[:n])) {
+				return
+			}
 			Cloned.Inc()
 		}
 	}
 }
 
-func (task *Task) goRx(rxch chan<- Buffer) {
-	defer task.WG.Done()
+func (task *Task) goRx() {
+	defer task.wg.Done()
+	defer task.svcwg.Done()
 
 	const minrxto = 10 * time.Millisecond
 	const maxrxto = 320 * time.Millisecond
@@ -385,73 +650,158 @@ func (task *Task) goRx(rxch chan<- Buffer) {
 	ptr := unsafe.Pointer(&rxbuf[0])
 	h := (*internal.MsgHeader)(ptr)
 
-	defer func() {
-		rxbuf = rxbuf[:0]
-		rxoob = rxoob[:0]
-		close(rxch)
-	}()
+	// breaks remaining of the dumps reissued after reconnect
+	resync := 0
+	// received a message from this connection
+	heard := false
 
 	for {
 		select {
-		case <-task.Stop:
+		case <-task.ctx.Done():
 			return
 		default:
 		}
-		task.RxErr = task.sock.SetReadDeadline(time.Now().Add(rxto))
-		if task.RxErr != nil {
-			break
+		task.mutex.Lock()
+		sock := task.sock
+		task.mutex.Unlock()
+		err := sock.SetReadDeadline(time.Now().Add(rxto))
+		if err != nil {
+			task.fail(&task.rxerr, err)
+			return
 		}
-		n, noob, flags, addr, err := task.sock.ReadMsgUnix(rxbuf, rxoob)
-		_ = noob
-		_ = flags
-		_ = addr
+		n, _, _, _, err := sock.ReadMsgUnix(rxbuf, rxoob)
 		select {
-		case <-task.Stop:
+		case <-task.ctx.Done():
 			return
 		default:
 		}
-		if n == 0 || isTimeout(err) {
+		if err == nil && n == 0 {
+			err = io.EOF
+		}
+		if isTimeout(err) {
 			if rxto < maxrxto {
 				rxto *= 2
 			}
 		} else if err != nil {
-			e, ok := err.(*os.SyscallError)
-			if !ok || e.Err.Error() != "EOF" {
-				task.RxErr = err
+			resync, err = task.reconnect(err, heard)
+			if err != nil {
+				task.fail(&task.rxerr, err)
+				return
 			}
-			break
-		} else if task.RxErr = h.Validate(rxbuf[:n]); task.RxErr != nil {
-			break
+			heard = false
+			rxto = minrxto
+		} else if err = h.Validate(rxbuf[:n]); err != nil {
+			task.metrics.Invalid[h.Kind].Inc()
+			task.fail(&task.rxerr, err)
+			return
+		} else if err = task.matchVersion(h.Version,
+			!heard); err != nil {
+			task.metrics.Invalid[h.Kind].Inc()
+			task.fail(&task.rxerr, err)
+			return
 		} else {
+			heard = true
 			rxto = minrxto
-			rxch <- cloneBuffer(rxbuf[:n])
+			task.metrics.rx(MsgKind(h.Kind))
+			if !task.sendMarks() {
+				return
+			}
+			if task.rec != nil {
+				task.rec.Msg(rxbuf[:n], DirectionIn)
+			}
+			if !task.send(cloneBuffer(rxbuf[:n])) {
+				return
+			}
 			Cloned.Inc()
+			if resync > 0 && h.Kind == internal.MsgKindBreak {
+				resync--
+				if resync == 0 &&
+					!task.note(internal.MsgKindResynced) {
+					return
+				}
+			}
 		}
 	}
 }
 
+// queue a task local note to the rx channel
+func (task *Task) note(kind uint8) bool {
+	buf := newBuffer(internal.SizeofMsg)
+	h := (*internal.MsgHeader)(buf.pointer())
+	h.SetLocal(kind)
+	if task.rec != nil {
+		task.rec.Msg(buf.bytes(), DirectionIn)
+	}
+	return task.send(buf)
+}
+
 func (task *Task) goTx(loch, hich <-chan buffer) {
-	defer task.WG.Done()
-	for task.TxErr == nil {
+	defer task.wg.Done()
+	defer task.svcwg.Done()
+	for {
+		var err error
 		select {
-		case <-task.Stop:
+		case <-task.ctx.Done():
 			return
 		case buf, ok := <-hich:
-			if ok {
-				task.TxErr = task.tx(buf, 0)
-			} else {
+			if !ok {
 				return
 			}
+			err = task.tx(buf, 0)
 		case buf, ok := <-loch:
-			if ok {
-				task.TxErr = task.tx(buf, 10*time.Millisecond)
-			} else {
+			if !ok {
 				return
 			}
+			err = task.tx(buf, 10*time.Millisecond)
+		case <-task.stats.wake:
+			err = task.flushStats()
+		}
+		if err != nil && task.redial.max > 0 && isDisconnect(err) {
+			// drop while rx reconnects
+			Dropped.Inc()
+		} else if err != nil {
+			task.fail(&task.txerr, err)
+			return
 		}
 	}
 }
 
+// Send through hi-priority channel; waits for the tx routine unless the
+// context is done or the task has stopped.
+func (task *Task) hi(ctx context.Context, buf buffer) error {
+	if err := task.stopped(ctx); err != nil {
+		buf.pool()
+		return err
+	}
+	select {
+	case task.hich <- buf:
+		return nil
+	case <-ctx.Done():
+		buf.pool()
+		return ctx.Err()
+	case <-task.ctx.Done():
+		buf.pool()
+		return task.stopped(ctx)
+	}
+}
+
+// stopped returns the context's error, the task's failure, ErrStopped, or
+// nil while the task runs.
+func (task *Task) stopped(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case <-task.ctx.Done():
+	default:
+		return nil
+	}
+	if err := task.failure(); err != nil {
+		return err
+	}
+	return ErrStopped
+}
+
 // Send through low-priority, leaky-bucket.
 func (task *Task) queue(buf buffer) {
 	select {
@@ -466,19 +816,37 @@ func (task *Task) tx(buf buffer, timeout time.Duration) error {
 	var oob []byte
 	var dl time.Time
 	defer buf.pool()
-	if task.sock == nil {
-		return io.EOF
-	}
 	if timeout != time.Duration(0) {
 		dl = time.Now().Add(timeout)
 	}
-	err := task.sock.SetWriteDeadline(dl)
+	switch kind(buf) {
+	case internal.MsgKindDumpIfInfo, internal.MsgKindDumpFibInfo:
+		// hold goRx from queueing the reply until the mark is pending
+		task.marks.Lock()
+		defer task.marks.Unlock()
+	}
+	if !task.speaks(kind(buf)) {
+		// the negotiated version lacks this kind
+		Dropped.Inc()
+		return nil
+	}
+	(*internal.MsgHeader)(buf.pointer()).Version = task.ProtocolVersion()
+	task.mutex.Lock()
+	sock := task.sock
+	task.mutex.Unlock()
+	err := sock.SetWriteDeadline(dl)
 	if err != nil {
 		return err
 	}
-	_, _, err = task.sock.WriteMsgUnix(buf.bytes(), oob, nil)
+	_, _, err = sock.WriteMsgUnix(buf.bytes(), oob, nil)
 	if err == nil {
 		Sent.Inc()
+		atomic.StoreUint32(&task.spoke, 1)
+		task.metrics.tx(MsgKind(kind(buf)))
+		if task.rec != nil {
+			task.rec.Msg(buf.bytes(), DirectionOut)
+		}
+		task.pendMark(kind(buf))
 		if kind(buf) == internal.MsgKindCarrier {
 			msg := (*internal.MsgCarrier)(buf.pointer())
 			xid := Xid(msg.Xid)
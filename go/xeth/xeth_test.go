@@ -0,0 +1,152 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xeth_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/xethtest"
+)
+
+const (
+	testMux  = "xethtest0"
+	testPeer = "xethtest1"
+)
+
+var testHa = net.HardwareAddr{0x02, 0, 0, 0, 0, 1}
+
+// start a task on a fake mux; skip if the veth stand-in can't be made
+func startTask(t *testing.T, opts ...xeth.TaskOption) (*xethtest.Mux,
+	*xeth.Task) {
+	t.Helper()
+	del, err := xethtest.Veth(testMux, testPeer)
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() {
+		del()
+		// forget the test's links
+		for _, xid := range xeth.ListXids() {
+			xeth.RxDelete(xid)
+		}
+	})
+	mux, err := xethtest.New(testMux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { mux.Close() })
+	task, err := xeth.StartContext(context.Background(), testMux, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { task.Close() })
+	if !mux.Accepted() {
+		t.Fatal("not accepted")
+	}
+	return mux, task
+}
+
+// parse the next note from the task
+func next(t *testing.T, task *xeth.Task) interface{} {
+	t.Helper()
+	select {
+	case buf, ok := <-task.RxCh:
+		if !ok {
+			t.Fatal("closed RxCh")
+		}
+		return xeth.Parse(buf)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+	return nil
+}
+
+// parse notes until a Break
+func untilBreak(t *testing.T, task *xeth.Task) (notes []interface{}) {
+	t.Helper()
+	for {
+		note := next(t, task)
+		if br, ok := note.(xeth.Break); ok {
+			return append(notes, br)
+		}
+		notes = append(notes, note)
+	}
+}
+
+func TestDumpIfInfo(t *testing.T) {
+	mux, task := startTask(t)
+	mux.IfInfo(xethtest.MsgIfInfo(3, "xeth1", 10, xeth.DevKindPort,
+		xethtest.ReasonDump, testHa))
+	if err := task.DumpIfInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	notes := untilBreak(t, task)
+	if len(notes) != 3 {
+		t.Fatalf("notes %v", notes)
+	}
+	if notes[0] != xeth.MarkIfInfo {
+		t.Error("mark", notes[0])
+	}
+	if notes[1] != xeth.DevNew(3) {
+		t.Error("new", notes[1])
+	}
+	if br := notes[2].(xeth.Break); len(br.Swept) != 0 {
+		t.Error("swept", br.Swept)
+	}
+	l := xeth.LinkOf(3)
+	if l == nil {
+		t.Fatal("no link")
+	}
+	if name := l.IfInfoName(); name != "xeth1" {
+		t.Error("name", name)
+	}
+	if err := task.Close(); err != nil {
+		t.Error("close", err)
+	}
+	if _, ok := <-task.RxCh; ok {
+		t.Error("open RxCh")
+	}
+	select {
+	case <-task.Done():
+	default:
+		t.Error("not done")
+	}
+}
+
+func TestStart(t *testing.T) {
+	del, err := xethtest.Veth(testMux, testPeer)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer del()
+	mux, err := xethtest.New(testMux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mux.Close()
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	task, err := xeth.Start(testMux, &wg, stop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.WG != &wg {
+		t.Error("WG")
+	}
+	go func() {
+		for range task.RxCh {
+		}
+	}()
+	close(stop)
+	wg.Wait()
+	if task.RxErr != nil || task.TxErr != nil {
+		t.Error(task.RxErr, task.TxErr)
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xethtest
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+// IfInfo reasons
+const (
+	ReasonNew      = internal.IfInfoReasonNew
+	ReasonDel      = internal.IfInfoReasonDel
+	ReasonUp       = internal.IfInfoReasonUp
+	ReasonDown     = internal.IfInfoReasonDown
+	ReasonDump     = internal.IfInfoReasonDump
+	ReasonReg      = internal.IfInfoReasonReg
+	ReasonUnreg    = internal.IfInfoReasonUnreg
+	ReasonFeatures = internal.IfInfoReasonFeatures
+)
+
+type NextHop struct {
+	Gw      net.IP
+	Ifindex int32
+	Weight  int32
+	Flags   xeth.RtnhFlags
+	Scope   xeth.RtScope
+}
+
+func newMsg(kind uint8, n int) []byte {
+	b := make([]byte, n)
+	h := (*internal.MsgHeader)(unsafe.Pointer(&b[0]))
+	h.Set(kind)
+	return b
+}
+
+func MsgBreak() []byte {
+	return newMsg(internal.MsgKindBreak, internal.SizeofMsgBreak)
+}
+
+// MsgIfInfo returns an IFINFO message of a link in the default netns.
+func MsgIfInfo(xid xeth.Xid, ifname string, ifindex int32, kind xeth.DevKind,
+	reason uint8, ha net.HardwareAddr) []byte {
+	b := newMsg(internal.MsgKindIfInfo, internal.SizeofMsgIfInfo)
+	msg := (*internal.MsgIfInfo)(unsafe.Pointer(&b[0]))
+	msg.Xid = uint32(xid)
+	copy(msg.Ifname[:internal.SizeofIfName-1], ifname)
+	msg.Net = uint64(xeth.DefaultNetNs)
+	msg.Ifindex = ifindex
+	copy(msg.Addr[:], ha)
+	msg.Kind = uint8(kind)
+	msg.Reason = reason
+	return b
+}
+
+// MsgIfInfoReg returns an IFINFO message of the link's netns change.
+func MsgIfInfoReg(xid xeth.Xid, netns xeth.NetNs, ifindex int32) []byte {
+	b := newMsg(internal.MsgKindIfInfo, internal.SizeofMsgIfInfo)
+	msg := (*internal.MsgIfInfo)(unsafe.Pointer(&b[0]))
+	msg.Xid = uint32(xid)
+	msg.Net = uint64(netns)
+	msg.Ifindex = ifindex
+	msg.Reason = internal.IfInfoReasonReg
+	return b
+}
+
+func MsgIfa(xid xeth.Xid, ipnet *net.IPNet, add bool) []byte {
+	b := newMsg(internal.MsgKindIfa, internal.SizeofMsgIfa)
+	msg := (*internal.MsgIfa)(unsafe.Pointer(&b[0]))
+	msg.Xid = uint32(xid)
+	msg.Event = internal.IFA_DEL
+	if add {
+		msg.Event = internal.IFA_ADD
+	}
+	msg.Address = be32(ipnet.IP.To4())
+	msg.Mask = be32(net.IP(ipnet.Mask).To4())
+	return b
+}
+
+func MsgIfa6(xid xeth.Xid, ipnet *net.IPNet, add bool) []byte {
+	b := newMsg(internal.MsgKindIfa6, internal.SizeofMsgIfa6)
+	msg := (*internal.MsgIfa6)(unsafe.Pointer(&b[0]))
+	msg.Xid = uint32(xid)
+	msg.Event = internal.IFA_DEL
+	if add {
+		msg.Event = internal.IFA_ADD
+	}
+	copy(msg.Address[:], ipnet.IP.To16())
+	ones, _ := ipnet.Mask.Size()
+	msg.Length = uint8(ones)
+	return b
+}
+
+func MsgEthtoolFlags(xid xeth.Xid, flags xeth.EthtoolFlagBits) []byte {
+	b := newMsg(internal.MsgKindEthtoolFlags,
+		internal.SizeofMsgEthtoolFlags)
+	msg := (*internal.MsgEthtoolFlags)(unsafe.Pointer(&b[0]))
+	msg.Xid = uint32(xid)
+	msg.Flags = uint32(flags)
+	return b
+}
+
+func MsgEthtoolPause(xid xeth.Xid, pause xeth.EthtoolPause) []byte {
+	b := newMsg(internal.MsgKindEthtoolPause,
+		internal.SizeofMsgEthtoolPause)
+	msg := (*internal.MsgEthtoolPause)(unsafe.Pointer(&b[0]))
+	msg.Xid = uint32(xid)
+	msg.Autoneg = u8(pause.AutoNeg)
+	msg.Rx_pause = u8(pause.Rx)
+	msg.Tx_pause = u8(pause.Tx)
+	return b
+}
+
+func MsgEthtoolFec(xid xeth.Xid, fec xeth.EthtoolFec) []byte {
+	b := newMsg(internal.MsgKindEthtoolFec,
+		internal.SizeofMsgEthtoolFec)
+	msg := (*internal.MsgEthtoolFec)(unsafe.Pointer(&b[0]))
+	msg.Xid = uint32(xid)
+	msg.Fec = uint32(fec.Configured)
+	msg.Active_fec = uint32(fec.Active)
+	return b
+}
+
+// MsgEthtoolEee sends the first word of each EEE link mode bitmap.
+func MsgEthtoolEee(xid xeth.Xid, eee xeth.EthtoolEee) []byte {
+	b := newMsg(internal.MsgKindEthtoolEee,
+		internal.SizeofMsgEthtoolEee)
+	msg := (*internal.MsgEthtoolEee)(unsafe.Pointer(&b[0]))
+	msg.Xid = uint32(xid)
+	msg.Supported = uint32(firstWord(eee.Supported))
+	msg.Advertised = uint32(firstWord(eee.Advertised))
+	msg.Lp_advertised = uint32(firstWord(eee.LPAdvertised))
+	msg.Tx_lpi_timer = eee.TxLpiTimer
+	msg.Eee_active = u8(eee.Active)
+	msg.Eee_enabled = u8(eee.Enabled)
+	msg.Tx_lpi_enabled = u8(eee.TxLpiEnabled)
+	return b
+}
+
+func firstWord(bits xeth.EthtoolLinkModeBits) uint64 {
+	if len(bits) == 0 {
+		return 0
+	}
+	return bits[0]
+}
+
+func u8(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func MsgEthtoolSettings(xid xeth.Xid, mbps uint32, duplex xeth.Duplex,
+	port xeth.DevPort, autoneg xeth.AutoNeg) []byte {
+	b := newMsg(internal.MsgKindEthtoolSettings,
+		internal.SizeofMsgEthtoolSettings)
+	msg := (*internal.MsgEthtoolSettings)(unsafe.Pointer(&b[0]))
+	msg.Xid = uint32(xid)
+	msg.Speed = mbps
+	msg.Duplex = uint8(duplex)
+	msg.Port = uint8(port)
+	msg.Autoneg = uint8(autoneg)
+	return b
+}
+
+// MsgLinkModes returns a link modes message of the given kind,
+// MsgKindEthtoolLinkModesSupported, Advertising, or LPAdvertising; with
+// more than one word of modes, the message is wider than the driver's.
+func MsgLinkModes(kind uint8, xid xeth.Xid,
+	modes xeth.EthtoolLinkModeBits) []byte {
+	n := internal.SizeofMsgEthtoolLinkModes
+	if len(modes) > 1 {
+		n += 8 * (len(modes) - 1)
+	}
+	b := newMsg(kind, n)
+	msg := (*internal.MsgEthtoolLinkModes)(unsafe.Pointer(&b[0]))
+	msg.Xid = uint32(xid)
+	copy(msg.Words(n), modes)
+	return b
+}
+
+// Link modes message kinds
+const (
+	MsgKindEthtoolLinkModesSupported     = internal.MsgKindEthtoolLinkModesSupported
+	MsgKindEthtoolLinkModesAdvertising   = internal.MsgKindEthtoolLinkModesAdvertising
+	MsgKindEthtoolLinkModesLPAdvertising = internal.MsgKindEthtoolLinkModesLPAdvertising
+)
+
+func MsgChangeUpperXid(upper, lower xeth.Xid, linking bool) []byte {
+	b := newMsg(internal.MsgKindChangeUpperXid,
+		internal.SizeofMsgChangeUpperXid)
+	msg := (*internal.MsgChangeUpperXid)(unsafe.Pointer(&b[0]))
+	msg.Upper = uint32(upper)
+	msg.Lower = uint32(lower)
+	if linking {
+		msg.Linking = 1
+	}
+	return b
+}
+
+// MsgFibEntry returns an IPv4 FIBENTRY message with the given next hops.
+func MsgFibEntry(netns xeth.NetNs, ipnet *net.IPNet, table xeth.RtTable,
+	event xeth.FibEntryEvent, rtn xeth.Rtn, nhs ...NextHop) []byte {
+	n := internal.SizeofMsgFibEntry + len(nhs)*internal.SizeofNextHop
+	b := newMsg(internal.MsgKindFibEntry, n)
+	msg := (*internal.MsgFibEntry)(unsafe.Pointer(&b[0]))
+	msg.Net = uint64(netns)
+	msg.Address = be32(ipnet.IP.To4())
+	msg.Mask = be32(net.IP(ipnet.Mask).To4())
+	msg.Event = uint8(event)
+	msg.Nhs = uint8(len(nhs))
+	msg.Type = uint8(rtn)
+	msg.Table = uint32(table)
+	msgnhs := msg.NextHops()
+	for i, nh := range nhs {
+		msgnhs[i] = internal.NextHop{
+			Ifindex: nh.Ifindex,
+			Weight:  nh.Weight,
+			Flags:   uint32(nh.Flags),
+			Gw:      be32(nh.Gw.To4()),
+			Scope:   uint8(nh.Scope),
+		}
+	}
+	return b
+}
+
+// MsgFib6Entry returns an IPv6 FIB6ENTRY message; the first next hop is
+// required and the rest are siblings.
+func MsgFib6Entry(netns xeth.NetNs, ipnet *net.IPNet, table xeth.RtTable,
+	event xeth.FibEntryEvent, rtn xeth.Rtn, nh NextHop,
+	siblings ...NextHop) []byte {
+	n := internal.SizeofMsgFib6Entry + len(siblings)*internal.SizeofNextHop6
+	b := newMsg(internal.MsgKindFib6Entry, n)
+	msg := (*internal.MsgFib6Entry)(unsafe.Pointer(&b[0]))
+	msg.Net = uint64(netns)
+	copy(msg.Address[:], ipnet.IP.To16())
+	ones, _ := ipnet.Mask.Size()
+	msg.Length = uint8(ones)
+	msg.Event = uint8(event)
+	msg.Nsiblings = uint8(len(siblings))
+	msg.Type = uint8(rtn)
+	msg.Table = uint32(table)
+	msg.Nh = nexthop6(nh)
+	msgsiblings := msg.Siblings()
+	for i, sibling := range siblings {
+		msgsiblings[i] = nexthop6(sibling)
+	}
+	return b
+}
+
+// MsgNeighUpdate returns a NEIGH_UPDATE message; a nil hardware address
+// deletes the neighbor.
+func MsgNeighUpdate(netns xeth.NetNs, ifindex int32, ip net.IP,
+	ha net.HardwareAddr) []byte {
+	b := newMsg(internal.MsgKindNeighUpdate,
+		internal.SizeofMsgNeighUpdate)
+	msg := (*internal.MsgNeighUpdate)(unsafe.Pointer(&b[0]))
+	msg.Net = uint64(netns)
+	msg.Ifindex = ifindex
+	if ip4 := ip.To4(); ip4 != nil {
+		msg.Family = syscall.AF_INET
+		msg.Len = net.IPv4len
+		copy(msg.Dst[:], ip4)
+	} else {
+		msg.Family = syscall.AF_INET6
+		msg.Len = net.IPv6len
+		copy(msg.Dst[:], ip.To16())
+	}
+	copy(msg.Lladdr[:], ha)
+	return b
+}
+
+func MsgNetNsAdd(netns xeth.NetNs) []byte {
+	return msgNetNs(internal.MsgKindNetNsAdd, netns)
+}
+
+func MsgNetNsDel(netns xeth.NetNs) []byte {
+	return msgNetNs(internal.MsgKindNetNsDel, netns)
+}
+
+func msgNetNs(kind uint8, netns xeth.NetNs) []byte {
+	b := newMsg(kind, internal.SizeofMsgNetNs)
+	msg := (*internal.MsgNetNs)(unsafe.Pointer(&b[0]))
+	msg.Net = uint64(netns)
+	return b
+}
+
+func nexthop6(nh NextHop) internal.NextHop6 {
+	nh6 := internal.NextHop6{
+		Ifindex: nh.Ifindex,
+		Weight:  nh.Weight,
+		Flags:   uint32(nh.Flags),
+	}
+	copy(nh6.Gw[:], nh.Gw.To16())
+	return nh6
+}
+
+// network ordered bytes as the host ordered __be32 of the uapi
+func be32(ip net.IP) (u uint32) {
+	if len(ip) == net.IPv4len {
+		u = *(*uint32)(unsafe.Pointer(&ip[0]))
+	}
+	return
+}
+
+// VersionOf returns the header version of a side-band message.
+func VersionOf(msg []byte) uint8 {
+	if len(msg) < internal.SizeofMsg {
+		return 0
+	}
+	return (*internal.MsgHeader)(unsafe.Pointer(&msg[0])).Version
+}
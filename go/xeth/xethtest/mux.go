@@ -0,0 +1,516 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This package provides an in-process stand-in for the xeth-mux driver so
+// that programs built on xeth.Start may be tested without the dkms module.
+//
+//	mux, err := xethtest.New("xethtest0")
+//	...
+//	mux.IfInfo(xethtest.MsgIfInfo(3, "xeth1", 10, xeth.DevKindPort,
+//		xethtest.ReasonDump, ha))
+//	task, err := xeth.Start("xethtest0", &wg, stop)
+//
+// The task's raw exception-frame socket needs a real netdev named like the
+// mux; see Veth.
+package xethtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/platinasystems/xeth/v3/go/xeth"
+	"github.com/platinasystems/xeth/v3/go/xeth/internal"
+)
+
+const unixpacket = "unixpacket"
+
+type Stat struct {
+	Xid   xeth.Xid
+	Index uint32
+}
+
+// Mux listens on the abstract side-band socket, "@" + Name, and answers the
+// task's requests with the scripted messages.
+type Mux struct {
+	Name string
+
+	// Version is the newest that the mux speaks, default
+	// internal.MsgVersion; set before the task connects to emulate an
+	// older driver. Like the driver, the mux closes a connection that
+	// sends a newer version, and otherwise rewrites the messages it sends
+	// with the version last received.
+	Version uint8
+
+	ln    *net.UnixListener
+	done  chan struct{}
+	wg    sync.WaitGroup
+	mutex sync.Mutex
+	conn  *net.UnixConn
+	connc *sync.Cond
+
+	ifinfo    [][]byte
+	fibinfo   [][]byte
+	fibdumped bool  // by the current connection
+	peer      uint8 // version received by the current connection
+
+	received     [][]byte
+	carrier      map[xeth.Xid]bool
+	speed        map[xeth.Xid]uint32
+	pause        map[xeth.Xid]xeth.EthtoolPause
+	fec          map[xeth.Xid]xeth.EthtoolFec
+	eee          map[xeth.Xid]xeth.EthtoolEee
+	linkStats    map[Stat]uint64
+	ethtoolStats map[Stat]uint64
+	invalid      int
+
+	rawfd  int
+	rawsa  syscall.SockaddrLinklayer
+	frames [][]byte
+}
+
+// Listen on the mux side-band socket and serve one task connection at a
+// time until Close.
+func New(name string) (*Mux, error) {
+	sa, err := net.ResolveUnixAddr(unixpacket, "@"+name)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.ListenUnix(unixpacket, sa)
+	if err != nil {
+		return nil, err
+	}
+	mux := &Mux{
+		Name:         name,
+		ln:           ln,
+		done:         make(chan struct{}),
+		carrier:      make(map[xeth.Xid]bool),
+		speed:        make(map[xeth.Xid]uint32),
+		pause:        make(map[xeth.Xid]xeth.EthtoolPause),
+		fec:          make(map[xeth.Xid]xeth.EthtoolFec),
+		eee:          make(map[xeth.Xid]xeth.EthtoolEee),
+		linkStats:    make(map[Stat]uint64),
+		ethtoolStats: make(map[Stat]uint64),
+		rawfd:        -1,
+	}
+	mux.connc = sync.NewCond(&mux.mutex)
+	mux.wg.Add(1)
+	go mux.goAccept()
+	return mux, nil
+}
+
+// Attach a raw socket to the given netdev, usually the peer of a veth pair
+// named like the mux, to inject frames into the task's exception path and to
+// capture the frames that it sends.
+func (mux *Mux) Attach(peer string) error {
+	ifi, err := net.InterfaceByName(peer)
+	if err != nil {
+		return err
+	}
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW,
+		int(xeth.ETH_P_ALL.Network()))
+	if err != nil {
+		return err
+	}
+	if err = syscall.BindToDevice(fd, peer); err != nil {
+		syscall.Close(fd)
+		return err
+	}
+	// wake the receiver to notice Close
+	tv := syscall.NsecToTimeval(int64(100 * time.Millisecond))
+	err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET,
+		syscall.SO_RCVTIMEO, &tv)
+	if err != nil {
+		syscall.Close(fd)
+		return err
+	}
+	mux.mutex.Lock()
+	mux.rawfd = fd
+	mux.rawsa = syscall.SockaddrLinklayer{
+		Protocol: xeth.ETH_P_ALL.Network(),
+		Ifindex:  ifi.Index,
+		Hatype:   syscall.ARPHRD_ETHER,
+	}
+	mux.mutex.Unlock()
+	mux.wg.Add(1)
+	go mux.goRawRx(fd, ifi.Index)
+	return nil
+}
+
+// Close the listener, any task connection, and raw socket then wait for the
+// service routines to finish.
+func (mux *Mux) Close() error {
+	err := mux.ln.Close()
+	mux.mutex.Lock()
+	close(mux.done)
+	if mux.conn != nil {
+		mux.conn.Close()
+	}
+	mux.connc.Broadcast()
+	mux.mutex.Unlock()
+	mux.wg.Wait()
+	if mux.rawfd >= 0 {
+		syscall.Close(mux.rawfd)
+		mux.rawfd = -1
+	}
+	return err
+}
+
+// Disconnect the current task, like an admin-down of the mux.
+func (mux *Mux) Disconnect() {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	if mux.conn != nil {
+		mux.conn.Close()
+		mux.conn = nil
+	}
+}
+
+// Wait for a task to connect; returns false if the mux is closed first.
+func (mux *Mux) Accepted() bool {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	for mux.conn == nil {
+		select {
+		case <-mux.done:
+			return false
+		default:
+		}
+		mux.connc.Wait()
+	}
+	return true
+}
+
+// IfInfo scripts the reply to DUMP_IFINFO; the mux follows it with a BREAK.
+func (mux *Mux) IfInfo(msgs ...[]byte) {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	mux.ifinfo = msgs
+}
+
+// FibInfo scripts the reply to DUMP_FIBINFO; the mux follows it with a BREAK.
+// Like the driver, the mux only replays the FIB with the first dump of each
+// connection.
+func (mux *Mux) FibInfo(msgs ...[]byte) {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	mux.fibinfo = msgs
+}
+
+// Send unsolicited messages to the connected task.
+func (mux *Mux) Send(msgs ...[]byte) error {
+	mux.mutex.Lock()
+	conn := mux.conn
+	mux.mutex.Unlock()
+	if conn == nil {
+		return io.ErrClosedPipe
+	}
+	for _, msg := range msgs {
+		if _, _, err := conn.WriteMsgUnix(mux.stamp(msg), nil,
+			nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Inject an exception frame through the attached peer.
+func (mux *Mux) Inject(frame []byte) error {
+	mux.mutex.Lock()
+	fd, sa := mux.rawfd, mux.rawsa
+	mux.mutex.Unlock()
+	if fd < 0 {
+		return errors.New("no attached peer")
+	}
+	return syscall.Sendto(fd, frame, 0, &sa)
+}
+
+// Carrier returns the last carrier state that the task sent for xid.
+func (mux *Mux) Carrier(xid xeth.Xid) (on, found bool) {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	on, found = mux.carrier[xid]
+	return
+}
+
+// Speed returns the last speed that the task sent for xid.
+func (mux *Mux) Speed(xid xeth.Xid) (mbps uint32, found bool) {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	mbps, found = mux.speed[xid]
+	return
+}
+
+// Pause returns the last pause that the task sent for xid.
+func (mux *Mux) Pause(xid xeth.Xid) (pause xeth.EthtoolPause, found bool) {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	pause, found = mux.pause[xid]
+	return
+}
+
+// Fec returns the last FEC that the task sent for xid.
+func (mux *Mux) Fec(xid xeth.Xid) (fec xeth.EthtoolFec, found bool) {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	fec, found = mux.fec[xid]
+	return
+}
+
+// Eee returns the last EEE that the task sent for xid.
+func (mux *Mux) Eee(xid xeth.Xid) (eee xeth.EthtoolEee, found bool) {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	eee, found = mux.eee[xid]
+	return
+}
+
+// LinkStat returns the last count that the task sent for the xid's stat.
+func (mux *Mux) LinkStat(xid xeth.Xid, stat xeth.LinkStat) (n uint64,
+	found bool) {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	n, found = mux.linkStats[Stat{xid, uint32(stat)}]
+	return
+}
+
+// EthtoolStat returns the last count that the task sent for the xid's stat.
+func (mux *Mux) EthtoolStat(xid xeth.Xid, index uint32) (n uint64, found bool) {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	n, found = mux.ethtoolStats[Stat{xid, index}]
+	return
+}
+
+// Received returns a copy of every valid message received from the task.
+func (mux *Mux) Received() [][]byte {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	return append([][]byte{}, mux.received...)
+}
+
+// Frames returns a copy of every frame captured from the attached peer.
+func (mux *Mux) Frames() [][]byte {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	return append([][]byte{}, mux.frames...)
+}
+
+// Invalid returns the number of malformed or unsupported messages received,
+// like the driver's sbrx-invalid counter.
+func (mux *Mux) Invalid() int {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	return mux.invalid
+}
+
+func (mux *Mux) goAccept() {
+	defer mux.wg.Done()
+	for {
+		conn, err := mux.ln.AcceptUnix()
+		if err != nil {
+			return
+		}
+		mux.mutex.Lock()
+		if mux.conn != nil {
+			mux.conn.Close()
+		}
+		mux.conn = conn
+		mux.fibdumped = false
+		mux.peer = 0
+		mux.connc.Broadcast()
+		mux.mutex.Unlock()
+		mux.serve(conn)
+		mux.mutex.Lock()
+		if mux.conn == conn {
+			mux.conn = nil
+		}
+		mux.mutex.Unlock()
+		conn.Close()
+	}
+}
+
+func (mux *Mux) serve(conn *net.UnixConn) {
+	buf := make([]byte, os.Getpagesize())
+	for {
+		n, _, _, _, err := conn.ReadMsgUnix(buf, nil)
+		if err != nil || n == 0 {
+			return
+		}
+		if err = mux.rx(conn, buf[:n]); err != nil {
+			return
+		}
+	}
+}
+
+func (mux *Mux) rx(conn *net.UnixConn, b []byte) error {
+	if len(b) < internal.SizeofMsg {
+		mux.inc()
+		return nil
+	}
+	h := (*internal.MsgHeader)(unsafe.Pointer(&b[0]))
+	if h.Z64 != 0 || h.Z32 != 0 || h.Z16 != 0 {
+		mux.inc()
+		return nil
+	}
+	mux.mutex.Lock()
+	newest := mux.version()
+	mux.mutex.Unlock()
+	if h.Version < internal.MinMsgVersion || h.Version > newest {
+		mux.inc()
+		return fmt.Errorf("version %d", h.Version)
+	}
+	if h.Version < internal.KindVersion(h.Kind) {
+		mux.inc()
+		return nil
+	}
+	var exact int
+	switch h.Kind {
+	case internal.MsgKindDumpIfInfo:
+		exact = internal.SizeofMsgDumpIfInfo
+	case internal.MsgKindDumpFibInfo:
+		exact = internal.SizeofMsgDumpFibInfo
+	case internal.MsgKindCarrier:
+		exact = internal.SizeofMsgCarrier
+	case internal.MsgKindSpeed:
+		exact = internal.SizeofMsgSpeed
+	case internal.MsgKindLinkStat, internal.MsgKindEthtoolStat:
+		exact = internal.SizeofMsgStat
+	case internal.MsgKindEthtoolPause:
+		exact = internal.SizeofMsgEthtoolPause
+	case internal.MsgKindEthtoolFec:
+		exact = internal.SizeofMsgEthtoolFec
+	case internal.MsgKindEthtoolEee:
+		exact = internal.SizeofMsgEthtoolEee
+	default:
+		mux.inc()
+		return nil
+	}
+	if len(b) < exact {
+		mux.inc()
+		return nil
+	}
+	mux.mutex.Lock()
+	mux.peer = h.Version
+	mux.received = append(mux.received, append([]byte{}, b...))
+	var reply [][]byte
+	switch h.Kind {
+	case internal.MsgKindDumpIfInfo:
+		reply = append(reply, mux.ifinfo...)
+		reply = append(reply, MsgBreak())
+	case internal.MsgKindDumpFibInfo:
+		if !mux.fibdumped {
+			reply = append(reply, mux.fibinfo...)
+			mux.fibdumped = true
+		}
+		reply = append(reply, MsgBreak())
+	case internal.MsgKindCarrier:
+		msg := (*internal.MsgCarrier)(unsafe.Pointer(&b[0]))
+		mux.carrier[xeth.Xid(msg.Xid)] = msg.Flag == internal.CarrierOn
+	case internal.MsgKindSpeed:
+		msg := (*internal.MsgSpeed)(unsafe.Pointer(&b[0]))
+		mux.speed[xeth.Xid(msg.Xid)] = msg.Mbps
+	case internal.MsgKindLinkStat:
+		msg := (*internal.MsgStat)(unsafe.Pointer(&b[0]))
+		mux.linkStats[Stat{xeth.Xid(msg.Xid), msg.Index}] = msg.Count
+	case internal.MsgKindEthtoolStat:
+		msg := (*internal.MsgStat)(unsafe.Pointer(&b[0]))
+		mux.ethtoolStats[Stat{xeth.Xid(msg.Xid), msg.Index}] = msg.Count
+	case internal.MsgKindEthtoolPause:
+		msg := (*internal.MsgEthtoolPause)(unsafe.Pointer(&b[0]))
+		mux.pause[xeth.Xid(msg.Xid)] = xeth.EthtoolPause{
+			AutoNeg: msg.Autoneg != 0,
+			Rx:      msg.Rx_pause != 0,
+			Tx:      msg.Tx_pause != 0,
+		}
+	case internal.MsgKindEthtoolFec:
+		msg := (*internal.MsgEthtoolFec)(unsafe.Pointer(&b[0]))
+		mux.fec[xeth.Xid(msg.Xid)] = xeth.EthtoolFec{
+			Configured: xeth.EthtoolFecBits(msg.Fec),
+			Active:     xeth.EthtoolFecBits(msg.Active_fec),
+		}
+	case internal.MsgKindEthtoolEee:
+		msg := (*internal.MsgEthtoolEee)(unsafe.Pointer(&b[0]))
+		mux.eee[xeth.Xid(msg.Xid)] = xeth.EthtoolEee{
+			Supported: xeth.EthtoolLinkModeBits{
+				uint64(msg.Supported)},
+			Advertised: xeth.EthtoolLinkModeBits{
+				uint64(msg.Advertised)},
+			LPAdvertised: xeth.EthtoolLinkModeBits{
+				uint64(msg.Lp_advertised)},
+			TxLpiTimer:   msg.Tx_lpi_timer,
+			Active:       msg.Eee_active != 0,
+			Enabled:      msg.Eee_enabled != 0,
+			TxLpiEnabled: msg.Tx_lpi_enabled != 0,
+		}
+	}
+	mux.mutex.Unlock()
+	for _, msg := range reply {
+		if _, _, err := conn.WriteMsgUnix(mux.stamp(msg), nil,
+			nil); err != nil {
+			return fmt.Errorf("reply: %w", err)
+		}
+	}
+	return nil
+}
+
+func (mux *Mux) version() uint8 {
+	if mux.Version == 0 {
+		return internal.MsgVersion
+	}
+	return mux.Version
+}
+
+// set the version of a side-band message to send
+func (mux *Mux) stamp(msg []byte) []byte {
+	if len(msg) >= internal.SizeofMsg {
+		mux.mutex.Lock()
+		peer := mux.peer
+		if peer == 0 {
+			peer = mux.version()
+		}
+		mux.mutex.Unlock()
+		h := (*internal.MsgHeader)(unsafe.Pointer(&msg[0]))
+		h.Version = peer
+	}
+	return msg
+}
+
+func (mux *Mux) inc() {
+	mux.mutex.Lock()
+	mux.invalid++
+	mux.mutex.Unlock()
+}
+
+func (mux *Mux) goRawRx(fd, ifindex int) {
+	defer mux.wg.Done()
+	buf := make([]byte, internal.SizeofJumboFrame)
+	for {
+		select {
+		case <-mux.done:
+			return
+		default:
+		}
+		n, from, err := syscall.Recvfrom(fd, buf, 0)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			continue
+		} else if err != nil {
+			return
+		}
+		sa, ok := from.(*syscall.SockaddrLinklayer)
+		if !ok || sa.Ifindex != ifindex ||
+			sa.Pkttype == syscall.PACKET_OUTGOING {
+			continue
+		}
+		mux.mutex.Lock()
+		mux.frames = append(mux.frames, append([]byte{}, buf[:n]...))
+		mux.mutex.Unlock()
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xethtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+)
+
+// Veth creates and brings up a veth pair to stand in for the mux netdev;
+// the task binds its raw socket to name and the Mux may Attach to peer.
+// This requires CAP_NET_ADMIN and iproute2.
+func Veth(name, peer string) (del func() error, err error) {
+	if err = ip("link", "add", name, "type", "veth",
+		"peer", "name", peer); err != nil {
+		return
+	}
+	del = func() error {
+		return ip("link", "del", name)
+	}
+	for _, dev := range []string{name, peer} {
+		// quiet the raw path of IPv6 autoconf and listener reports
+		ioutil.WriteFile(filepath.Join("/proc/sys/net/ipv6/conf", dev,
+			"disable_ipv6"), []byte("1"), 0644)
+		if err = ip("link", "set", dev, "up"); err != nil {
+			del()
+			return nil, err
+		}
+	}
+	return
+}
+
+func ip(args ...string) error {
+	out, err := exec.Command("ip", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ip %v: %v: %s", args, err, out)
+	}
+	return nil
+}